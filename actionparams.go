@@ -0,0 +1,327 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package charm
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	gjs "github.com/juju/gojsonschema"
+	"gopkg.in/yaml.v2"
+)
+
+// ParamError describes a single invalid action parameter.
+type ParamError struct {
+	// Field holds the dotted path of the parameter, or "(root)"
+	// if the error concerns the parameters as a whole.
+	Field string
+
+	// Message describes the problem.
+	Message string
+}
+
+func (err *ParamError) Error() string {
+	return fmt.Sprintf("%s: %s", err.Field, err.Message)
+}
+
+// ParamsError holds all the problems found by
+// ActionSpec.ParseParams, sorted by field.
+type ParamsError struct {
+	Errors []*ParamError
+}
+
+func (err *ParamsError) Error() string {
+	switch len(err.Errors) {
+	case 0:
+		return "no params errors!"
+	case 1:
+		return err.Errors[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", err.Errors[0], len(err.Errors)-1)
+}
+
+// ParseParams returns the action parameters given as strings, such as
+// key=value arguments from a command line. A key may be a dotted path
+// such as "backup.target" to set a property of an object parameter.
+//
+// Each value is converted to the type given by the params schema for its
+// key: integers, numbers and booleans are parsed; arrays may be given as
+// a comma-separated list of items or as a YAML flow sequence; and objects
+// may be given as a YAML flow mapping. Values for keys that are not in
+// the schema, or whose type is string, are left as strings. Defaults are
+// then inserted as by InsertDefaults, and the result is validated as by
+// ValidateParams. Any problems are returned as a *ParamsError; a missing
+// required parameter is reported with the field of that parameter.
+func (spec *ActionSpec) ParseParams(args map[string]string) (map[string]interface{}, error) {
+	keys := make([]string, 0, len(args))
+	for key := range args {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	params := make(map[string]interface{})
+	var errs []*ParamError
+	for _, key := range keys {
+		if err := spec.setParam(params, key, args[key]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, newParamsError(errs)
+	}
+	insertParamDefaults(params, spec.Params)
+	result, err := spec.validate(params)
+	if err != nil {
+		return nil, err
+	}
+	if result.Valid() {
+		return params, nil
+	}
+	for _, resultErr := range result.Errors() {
+		errs = append(errs, newParamError(resultErr))
+	}
+	return nil, newParamsError(errs)
+}
+
+// requiredPattern matches the description of a gojsonschema
+// error for a missing required property.
+var requiredPattern = regexp.MustCompile(`^"(.*)" property is missing and required$`)
+
+// newParamError returns the *ParamError describing the given schema
+// validation error. Fields are given as dotted paths relative to the
+// parameters; errors for missing required properties, which gojsonschema
+// reports against the enclosing object, are given the missing property's
+// field.
+func newParamError(resultErr gjs.ResultError) *ParamError {
+	field := "(root)"
+	if resultErr.Context != nil {
+		field = resultErr.Context.String()
+	}
+	message := resultErr.Description
+	if m := requiredPattern.FindStringSubmatch(message); m != nil {
+		field += "." + m[1]
+		message = "is missing and required"
+	}
+	if field != "(root)" {
+		field = strings.TrimPrefix(field, "(root).")
+	}
+	return &ParamError{field, message}
+}
+
+// insertParamDefaults inserts the default values given by the object
+// schema into params, as InsertDefaults does, stepping into object
+// values and creating objects that have properties with defaults.
+// Unlike InsertDefaults, it allows object schemas without properties.
+func insertParamDefaults(params, schema map[string]interface{}) {
+	properties, _ := schema["properties"].(map[string]interface{})
+	for name, property := range properties {
+		propertySchema, ok := property.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if value, ok := params[name]; ok {
+			if inner, ok := value.(map[string]interface{}); ok {
+				insertParamDefaults(inner, propertySchema)
+			}
+			continue
+		}
+		if value, ok := propertySchema["default"]; ok {
+			params[name] = value
+			continue
+		}
+		inner := make(map[string]interface{})
+		insertParamDefaults(inner, propertySchema)
+		if len(inner) > 0 {
+			params[name] = inner
+		}
+	}
+}
+
+// newParamsError returns a *ParamsError holding
+// the given errors sorted by field.
+func newParamsError(errs []*ParamError) *ParamsError {
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errs[i].Field < errs[j].Field
+	})
+	return &ParamsError{errs}
+}
+
+// validate validates params against the params schema.
+func (spec *ActionSpec) validate(params map[string]interface{}) (*gjs.Result, error) {
+	schema, err := gjs.NewSchema(gjs.NewGoLoader(spec.Params))
+	if err != nil {
+		return nil, err
+	}
+	return schema.Validate(gjs.NewGoLoader(params))
+}
+
+// setParam sets the parameter with the given dotted key in params
+// to the given string, converted according to the params schema.
+func (spec *ActionSpec) setParam(params map[string]interface{}, key, str string) *ParamError {
+	path := strings.Split(key, ".")
+	schema := spec.Params
+	target := params
+	for i, name := range path {
+		if name == "" {
+			return &ParamError{key, "empty name in key"}
+		}
+		schema = propertySchema(schema, name)
+		if i == len(path)-1 {
+			break
+		}
+		switch next := target[name].(type) {
+		case nil:
+			m := make(map[string]interface{})
+			target[name] = m
+			target = m
+		case map[string]interface{}:
+			target = next
+		default:
+			return &ParamError{strings.Join(path[:i+1], "."), "cannot set both a value and properties"}
+		}
+	}
+	name := path[len(path)-1]
+	if _, ok := target[name]; ok {
+		return &ParamError{key, "cannot set both a value and properties"}
+	}
+	value, err := coerceParam(schema, str)
+	if err != nil {
+		return &ParamError{key, err.Error()}
+	}
+	target[name] = value
+	return nil
+}
+
+// propertySchema returns the schema of the named property
+// of objects described by schema, or nil if it is unknown.
+func propertySchema(schema map[string]interface{}, name string) map[string]interface{} {
+	properties, _ := schema["properties"].(map[string]interface{})
+	property, _ := properties[name].(map[string]interface{})
+	return property
+}
+
+// schemaTypes returns the types allowed by the given schema.
+func schemaTypes(schema map[string]interface{}) []string {
+	switch t := schema["type"].(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		var types []string
+		for _, t := range t {
+			if s, ok := t.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+// coerceParam converts str to a value of the first type allowed
+// by the given schema that it can be converted to. If there is
+// none but the schema allows strings, or the schema does not
+// give a type, str is returned.
+func coerceParam(schema map[string]interface{}, str string) (interface{}, error) {
+	types := schemaTypes(schema)
+	if len(types) == 0 {
+		return str, nil
+	}
+	for _, t := range types {
+		if value, ok := coerceParamType(schema, t, str); ok {
+			return value, nil
+		}
+	}
+	for _, t := range types {
+		if t == "string" {
+			return str, nil
+		}
+	}
+	return nil, fmt.Errorf("expected %s, got %q", strings.Join(types, " or "), str)
+}
+
+func coerceParamType(schema map[string]interface{}, t, str string) (interface{}, bool) {
+	switch t {
+	case "integer":
+		if i, err := strconv.ParseInt(str, 10, 64); err == nil {
+			return i, true
+		}
+	case "number":
+		if f, err := strconv.ParseFloat(str, 64); err == nil {
+			return f, true
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(str); err == nil {
+			return b, true
+		}
+	case "null":
+		if str == "null" {
+			return nil, true
+		}
+	case "array":
+		if strings.HasPrefix(strings.TrimSpace(str), "[") {
+			var items []interface{}
+			if err := yaml.Unmarshal([]byte(str), &items); err != nil {
+				return nil, false
+			}
+			return stringKeyed(items)
+		}
+		items := []interface{}{}
+		if strings.TrimSpace(str) == "" {
+			return items, true
+		}
+		itemSchema, _ := schema["items"].(map[string]interface{})
+		for _, field := range strings.Split(str, ",") {
+			item, err := coerceParam(itemSchema, strings.TrimSpace(field))
+			if err != nil {
+				return nil, false
+			}
+			items = append(items, item)
+		}
+		return items, true
+	case "object":
+		var m map[string]interface{}
+		if err := yaml.Unmarshal([]byte(str), &m); err != nil || m == nil {
+			return nil, false
+		}
+		return stringKeyed(m)
+	}
+	return nil, false
+}
+
+// stringKeyed returns v with any maps unmarshaled from YAML converted
+// to maps keyed by strings, as required for JSON schema validation.
+func stringKeyed(v interface{}) (interface{}, bool) {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{})
+		for key, value := range v {
+			s, ok := key.(string)
+			if !ok {
+				return nil, false
+			}
+			m[s] = value
+		}
+		return stringKeyed(m)
+	case map[string]interface{}:
+		for key, value := range v {
+			value, ok := stringKeyed(value)
+			if !ok {
+				return nil, false
+			}
+			v[key] = value
+		}
+	case []interface{}:
+		for i, value := range v {
+			value, ok := stringKeyed(value)
+			if !ok {
+				return nil, false
+			}
+			v[i] = value
+		}
+	}
+	return v, true
+}
@@ -0,0 +1,139 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package charm_test
+
+import (
+	"strings"
+
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"gopkg.in/juju/charm.v6"
+)
+
+type ActionParamsSuite struct {
+	spec charm.ActionSpec
+}
+
+var _ = gc.Suite(&ActionParamsSuite{})
+
+func (s *ActionParamsSuite) SetUpTest(c *gc.C) {
+	actions, err := charm.ReadActionsYaml(strings.NewReader(`
+backup:
+    description: Take a backup.
+    params:
+        target:
+            type: string
+        count:
+            type: integer
+            default: 1
+        ratio:
+            type: number
+        compress:
+            type: boolean
+        tags:
+            type: array
+            items:
+                type: integer
+        limit:
+            type: [integer, string]
+        storage:
+            type: object
+            properties:
+                region:
+                    type: string
+                size:
+                    type: integer
+        extra:
+            type: object
+    required: [target]
+`))
+	c.Assert(err, gc.IsNil)
+	s.spec = actions.ActionSpecs["backup"]
+}
+
+func (s *ActionParamsSuite) TestParseParams(c *gc.C) {
+	params, err := s.spec.ParseParams(map[string]string{
+		"target":         "/srv",
+		"ratio":          "0.5",
+		"compress":       "true",
+		"tags":           "1, 2,3",
+		"limit":          "unlimited",
+		"storage.region": "eu",
+		"storage.size":   "10",
+		"extra":          "{a: 1, b: {c: x}}",
+		"undeclared":     "42",
+	})
+	c.Assert(err, gc.IsNil)
+	c.Assert(params, jc.DeepEquals, map[string]interface{}{
+		"target":   "/srv",
+		"count":    1,
+		"ratio":    0.5,
+		"compress": true,
+		"tags":     []interface{}{int64(1), int64(2), int64(3)},
+		"limit":    "unlimited",
+		"storage": map[string]interface{}{
+			"region": "eu",
+			"size":   int64(10),
+		},
+		"extra": map[string]interface{}{
+			"a": 1,
+			"b": map[string]interface{}{"c": "x"},
+		},
+		"undeclared": "42",
+	})
+}
+
+func (s *ActionParamsSuite) TestParseParamsYAMLArray(c *gc.C) {
+	params, err := s.spec.ParseParams(map[string]string{
+		"target": "x",
+		"tags":   "[4, 5]",
+		"limit":  "7",
+	})
+	c.Assert(err, gc.IsNil)
+	c.Assert(params["tags"], jc.DeepEquals, []interface{}{4, 5})
+	c.Assert(params["limit"], gc.Equals, int64(7))
+}
+
+func (s *ActionParamsSuite) TestParseParamsCoercionErrors(c *gc.C) {
+	_, err := s.spec.ParseParams(map[string]string{
+		"count":         "many",
+		"compress":      "perhaps",
+		"tags":          "1,x",
+		"target":        "/srv",
+		"target.path":   "/srv",
+		"storage.size":  "big",
+		"storage..size": "1",
+	})
+	c.Assert(err, gc.FitsTypeOf, &charm.ParamsError{})
+	c.Assert(errorStrings(err.(*charm.ParamsError)), jc.DeepEquals, []string{
+		`compress: expected boolean, got "perhaps"`,
+		`count: expected integer, got "many"`,
+		`storage..size: empty name in key`,
+		`storage.size: expected integer, got "big"`,
+		`tags: expected array, got "1,x"`,
+		`target: cannot set both a value and properties`,
+	})
+}
+
+func (s *ActionParamsSuite) TestParseParamsValidationErrors(c *gc.C) {
+	_, err := s.spec.ParseParams(map[string]string{
+		"storage.size": "10",
+	})
+	c.Assert(err, gc.FitsTypeOf, &charm.ParamsError{})
+	paramsErr := err.(*charm.ParamsError)
+	c.Assert(paramsErr.Errors, gc.HasLen, 1)
+	c.Assert(paramsErr.Errors[0], jc.DeepEquals, &charm.ParamError{
+		Field:   "target",
+		Message: "is missing and required",
+	})
+}
+
+func errorStrings(err *charm.ParamsError) []string {
+	var s []string
+	for _, e := range err.Errors {
+		s = append(s, e.Error())
+	}
+	return s
+}
@@ -41,28 +41,45 @@ func NewActions() *Actions {
 type ActionSpec struct {
 	Description string
 	Params      map[string]interface{}
+
+	// Parallel records whether the action may run on a unit at
+	// the same time as other actions.
+	Parallel bool
+
+	// ExecutionGroup holds the name of a group of actions of which
+	// only one may run on a unit at a time, or is empty if the action
+	// is not in a group.
+	ExecutionGroup string
+
+	// Results holds a JSON-Schema (draft 4) document describing
+	// the results of the action, or is nil if they are not
+	// described.
+	Results map[string]interface{}
+
+	// Access holds the access level that a user requires to run the
+	// action: one of ActionAccessRead, ActionAccessWrite or
+	// ActionAccessAdmin. It is empty if the charm does not specify it.
+	Access string
 }
 
+// The access levels that may be required to run an action.
+const (
+	ActionAccessRead  = "read"
+	ActionAccessWrite = "write"
+	ActionAccessAdmin = "admin"
+)
+
 // ValidateParams validates the passed params map against the given ActionSpec
 // and returns any error encountered.
 // Usage:
 //   err := ch.Actions().ActionSpecs["snapshot"].ValidateParams(someMap)
 func (spec *ActionSpec) ValidateParams(params map[string]interface{}) error {
-	// Load the schema from the Charm.
-	specLoader := gjs.NewGoLoader(spec.Params)
-	schema, err := gjs.NewSchema(specLoader)
-	if err != nil {
-		return err
-	}
-
-	// Load the params as a document to validate.
 	// If an empty map was passed, we need an empty map to validate against.
 	p := map[string]interface{}{}
 	if len(params) > 0 {
 		p = params
 	}
-	docLoader := gjs.NewGoLoader(p)
-	results, err := schema.Validate(docLoader)
+	results, err := spec.validate(p)
 	if err != nil {
 		return err
 	}
@@ -78,6 +95,35 @@ func (spec *ActionSpec) ValidateParams(params map[string]interface{}) error {
 	return errors.Errorf("validation failed: %s", strings.Join(errorStrings, "; "))
 }
 
+// ValidateResults validates the passed results map against the results
+// schema of the given ActionSpec and returns any error encountered. Any
+// results are valid if the spec has no results schema.
+func (spec *ActionSpec) ValidateResults(results map[string]interface{}) error {
+	if spec.Results == nil {
+		return nil
+	}
+	schema, err := gjs.NewSchema(gjs.NewGoLoader(spec.Results))
+	if err != nil {
+		return err
+	}
+	r := map[string]interface{}{}
+	if len(results) > 0 {
+		r = results
+	}
+	validation, err := schema.Validate(gjs.NewGoLoader(r))
+	if err != nil {
+		return err
+	}
+	if validation.Valid() {
+		return nil
+	}
+	var errorStrings []string
+	for _, validationError := range validation.Errors() {
+		errorStrings = append(errorStrings, validationError.String())
+	}
+	return errors.Errorf("results validation failed: %s", strings.Join(errorStrings, "; "))
+}
+
 // InsertDefaults inserts the schema's default values in target using
 // github.com/juju/gojsonschema.  If a nil target is received, an empty map
 // will be created as the target.  The target is then mutated to include the
@@ -122,6 +168,7 @@ func ReadActionsYaml(r io.Reader) (*Actions, error) {
 		}
 
 		desc := "No description"
+		spec := ActionSpec{}
 		thisActionSchema := map[string]interface{}{
 			"description": desc,
 			"type":        "object",
@@ -166,6 +213,42 @@ func ReadActionsYaml(r io.Reader) (*Actions, error) {
 					return nil, errors.New("params failed to parse as a map")
 				}
 				thisActionSchema["properties"] = typed
+			case "parallel":
+				typed, ok := value.(bool)
+				if !ok {
+					return nil, errors.Errorf("value for action key %q must be a boolean", key)
+				}
+				spec.Parallel = typed
+			case "execution-group":
+				typed, ok := value.(string)
+				if !ok || typed == "" {
+					return nil, errors.Errorf("value for action key %q must be a non-empty string", key)
+				}
+				spec.ExecutionGroup = typed
+			case "access":
+				typed, ok := value.(string)
+				if !ok {
+					return nil, errors.Errorf("value for action key %q must be a string", key)
+				}
+				switch typed {
+				case ActionAccessRead, ActionAccessWrite, ActionAccessAdmin:
+				default:
+					return nil, errors.Errorf("action %s has unknown access level %q", name, typed)
+				}
+				spec.Access = typed
+			case "results":
+				cleansedResults, err := cleanse(value)
+				if err != nil {
+					return nil, err
+				}
+				typed, ok := cleansedResults.(map[string]interface{})
+				if !ok {
+					return nil, errors.New("results failed to parse as a map")
+				}
+				if _, err := gjs.NewSchema(gjs.NewGoLoader(typed)); err != nil {
+					return nil, errors.Annotatef(err, "invalid results schema for action schema %s", name)
+				}
+				spec.Results = typed
 			default:
 				// In case this has nested maps, we must clean them out.
 				typed, err := cleanse(value)
@@ -185,10 +268,9 @@ func ReadActionsYaml(r io.Reader) (*Actions, error) {
 		}
 
 		// Now assign the resulting schema to the final entry for the result.
-		result.ActionSpecs[name] = ActionSpec{
-			Description: desc,
-			Params:      thisActionSchema,
-		}
+		spec.Description = desc
+		spec.Params = thisActionSchema
+		result.ActionSpecs[name] = spec
 	}
 	return result, nil
 }
@@ -961,3 +961,95 @@ func getSchemaForAction(c *gc.C, wholeSchema string) ActionSpec {
 	// Same action name for all tests, "act".
 	return loadedActions.ActionSpecs["act"]
 }
+
+func (s *ActionsSuite) TestReadActionMetadata(c *gc.C) {
+	spec := getSchemaForAction(c, `
+act:
+  description: Back up the database.
+  parallel: true
+  execution-group: database
+  access: admin
+  params:
+    outfile:
+      type: string
+  results:
+    type: object
+    properties:
+      size:
+        type: integer
+    required: [size]
+`)
+	c.Assert(spec.Parallel, jc.IsTrue)
+	c.Assert(spec.ExecutionGroup, gc.Equals, "database")
+	c.Assert(spec.Access, gc.Equals, ActionAccessAdmin)
+	c.Assert(spec.Results, jc.DeepEquals, map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"size": map[string]interface{}{"type": "integer"},
+		},
+		"required": []interface{}{"size"},
+	})
+	// The metadata is not part of the params schema.
+	c.Assert(spec.Params, jc.DeepEquals, map[string]interface{}{
+		"title":       "act",
+		"description": "Back up the database.",
+		"type":        "object",
+		"properties": map[string]interface{}{
+			"outfile": map[string]interface{}{"type": "string"},
+		},
+	})
+
+	spec = getSchemaForAction(c, "act:\n  description: d\n")
+	c.Assert(spec.Parallel, jc.IsFalse)
+	c.Assert(spec.ExecutionGroup, gc.Equals, "")
+	c.Assert(spec.Access, gc.Equals, "")
+	c.Assert(spec.Results, gc.IsNil)
+}
+
+func (s *ActionsSuite) TestReadBadActionMetadata(c *gc.C) {
+	for i, test := range []struct {
+		yaml        string
+		expectedErr string
+	}{{
+		yaml:        "act:\n  parallel: yes please\n",
+		expectedErr: `value for action key "parallel" must be a boolean`,
+	}, {
+		yaml:        "act:\n  execution-group: \"\"\n",
+		expectedErr: `value for action key "execution-group" must be a non-empty string`,
+	}, {
+		yaml:        "act:\n  access: root\n",
+		expectedErr: `action act has unknown access level "root"`,
+	}, {
+		yaml:        "act:\n  results: [a, b]\n",
+		expectedErr: `results failed to parse as a map`,
+	}, {
+		yaml:        "act:\n  results:\n    type: 5\n",
+		expectedErr: `invalid results schema for action schema act: .*`,
+	}} {
+		c.Logf("test %d: %s", i, test.yaml)
+		_, err := ReadActionsYaml(bytes.NewReader([]byte(test.yaml)))
+		c.Check(err, gc.ErrorMatches, test.expectedErr)
+	}
+}
+
+func (s *ActionsSuite) TestValidateResults(c *gc.C) {
+	spec := getSchemaForAction(c, `
+act:
+  results:
+    type: object
+    properties:
+      size:
+        type: integer
+    required: [size]
+`)
+	c.Assert(spec.ValidateResults(map[string]interface{}{"size": 10}), jc.ErrorIsNil)
+
+	err := spec.ValidateResults(map[string]interface{}{"size": "big"})
+	c.Assert(err, gc.ErrorMatches, "results validation failed: .*size.*")
+
+	err = spec.ValidateResults(nil)
+	c.Assert(err, gc.ErrorMatches, "results validation failed: .*size.*required.*")
+
+	spec = getSchemaForAction(c, "act:\n  description: d\n")
+	c.Assert(spec.ValidateResults(map[string]interface{}{"anything": true}), jc.ErrorIsNil)
+}
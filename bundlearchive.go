@@ -97,3 +97,15 @@ func (a *BundleArchive) ExpandTo(dir string) error {
 	defer zipr.Close()
 	return ziputil.ExtractAll(zipr.Reader, dir)
 }
+
+// ExpandToWithOptions is like ExpandTo but enforces the limits in
+// opts, and rejects any entry or symbolic link that would lead out
+// of dir. See CharmArchive.ExpandToWithOptions.
+func (a *BundleArchive) ExpandToWithOptions(dir string, opts ExpandOptions) error {
+	zipr, err := a.zopen.openZip()
+	if err != nil {
+		return err
+	}
+	defer zipr.Close()
+	return expandArchive(zipr.Reader, dir, opts)
+}
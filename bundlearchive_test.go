@@ -96,3 +96,17 @@ func (s *BundleArchiveSuite) TestExpandTo(c *gc.C) {
 	c.Assert(bdir.ReadMe(), gc.Equals, archive.ReadMe())
 	c.Assert(bdir.Data(), gc.DeepEquals, archive.Data())
 }
+
+func (s *BundleArchiveSuite) TestExpandToWithOptions(c *gc.C) {
+	dir := c.MkDir()
+	archive, err := charm.ReadBundleArchive(s.archivePath)
+	c.Assert(err, gc.IsNil)
+	err = archive.ExpandToWithOptions(dir, charm.ExpandOptions{MaxFiles: 1})
+	c.Assert(err, gc.FitsTypeOf, &charm.FileCountExceededError{})
+
+	err = archive.ExpandToWithOptions(dir, charm.ExpandOptions{MaxFiles: 100})
+	c.Assert(err, gc.IsNil)
+	bdir, err := charm.ReadBundleDir(dir)
+	c.Assert(err, gc.IsNil)
+	c.Assert(bdir.Data(), gc.DeepEquals, archive.Data())
+}
@@ -0,0 +1,378 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package charm
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// The methods of the changes returned by BundleData.ChangePlan.
+const (
+	BundleChangeAddCharm       = "addCharm"
+	BundleChangeAddMachine     = "addMachine"
+	BundleChangeAddApplication = "addApplication"
+	BundleChangeAddUnit        = "addUnit"
+	BundleChangeAddRelation    = "addRelation"
+	BundleChangeSetAnnotations = "setAnnotations"
+	BundleChangeExpose         = "expose"
+)
+
+// BundleChange describes a single change to be made
+// when deploying a bundle.
+type BundleChange struct {
+	// Id uniquely identifies the change within the plan,
+	// for example "addCharm-0".
+	Id string
+
+	// Method holds the kind of change, one of the
+	// BundleChange* constants.
+	Method string
+
+	// Params holds the parameters of the change. Its type
+	// depends on the method; for example, it holds an
+	// *AddCharmParams for an addCharm change. Parameters
+	// that refer to an entity created by another change
+	// hold the id of that change prefixed with "$", for
+	// example "$addApplication-1".
+	Params interface{}
+
+	// Requires holds the ids of the changes that must be
+	// made before this one. Changes that do not require
+	// one another may be made in parallel.
+	Requires []string
+}
+
+// AddCharmParams holds the parameters of an addCharm change.
+type AddCharmParams struct {
+	// Charm holds the URL or local path of the charm.
+	Charm string
+
+	// Series holds the series the charm is to be used for.
+	Series string
+}
+
+// AddMachineParams holds the parameters of an addMachine change.
+type AddMachineParams struct {
+	Series      string
+	Constraints string
+
+	// ContainerType holds the type of container
+	// to create, or is empty for a machine.
+	ContainerType string
+
+	// ParentId holds a placeholder for the machine
+	// or unit in whose machine a container is to be
+	// created. If empty, the container is created on
+	// a new machine.
+	ParentId string
+}
+
+// AddApplicationParams holds the parameters
+// of an addApplication change.
+type AddApplicationParams struct {
+	// Charm holds a placeholder for the charm added
+	// by an addCharm change.
+	Charm string
+
+	Application      string
+	Series           string
+	Options          map[string]interface{}
+	Constraints      string
+	Storage          map[string]string
+	Devices          map[string]string
+	EndpointBindings map[string]string
+	Resources        map[string]interface{}
+	Plan             string
+}
+
+// AddUnitParams holds the parameters of an addUnit change.
+type AddUnitParams struct {
+	// Application holds a placeholder for the application.
+	Application string
+
+	// To holds a placeholder for the machine on which the
+	// unit is to be placed, or for a unit with whose machine
+	// it is to be co-located. If empty, the unit is placed
+	// on a new machine.
+	To string
+}
+
+// AddRelationParams holds the parameters of an addRelation change.
+// Each endpoint holds a placeholder for an application, followed by
+// a colon and the relation name when it is known.
+type AddRelationParams struct {
+	Endpoint1 string
+	Endpoint2 string
+}
+
+// SetAnnotationsParams holds the parameters
+// of a setAnnotations change.
+type SetAnnotationsParams struct {
+	// Id holds a placeholder for the annotated entity.
+	Id string
+
+	// EntityType holds "application" or "machine".
+	EntityType  string
+	Annotations map[string]string
+}
+
+// ExposeParams holds the parameters of an expose change.
+type ExposeParams struct {
+	// Application holds a placeholder for the application.
+	Application string
+}
+
+// ChangePlan returns the changes needed to deploy the bundle, in an
+// order in which they can be made: charms are added first, then
+// applications with their annotations and exposure, then machines,
+// relations and finally units, whose placement directives are resolved
+// to the machines and containers they require.
+//
+// If charms is not nil, it should hold an entry for each charm
+// returned by bd.RequiredCharms, and is used to infer the relation
+// names of endpoints that do not specify them.
+//
+// The bundle data should be verified before ChangePlan is called;
+// ChangePlan returns an error only if it cannot resolve the
+// placements or relations.
+func (bd *BundleData) ChangePlan(charms map[string]Charm) ([]*BundleChange, error) {
+	p := &changePlanner{
+		bd:         bd,
+		charms:     charms,
+		charmIds:   make(map[string]string),
+		appIds:     make(map[string]string),
+		machineIds: make(map[string]string),
+		unitIds:    make(map[string]string),
+	}
+	p.addApplications()
+	p.addMachines()
+	if err := p.addRelations(); err != nil {
+		return nil, err
+	}
+	if err := p.addUnits(); err != nil {
+		return nil, err
+	}
+	return p.changes, nil
+}
+
+type changePlanner struct {
+	bd      *BundleData
+	charms  map[string]Charm
+	changes []*BundleChange
+
+	// charmIds, appIds, machineIds and unitIds hold the ids of
+	// the changes that add each charm, application, bundle
+	// machine and unit, such as "wordpress/0".
+	charmIds   map[string]string
+	appIds     map[string]string
+	machineIds map[string]string
+	unitIds    map[string]string
+}
+
+// add adds a change to the plan and returns its id.
+func (p *changePlanner) add(method string, params interface{}, requires ...string) string {
+	id := fmt.Sprintf("%s-%d", method, len(p.changes))
+	p.changes = append(p.changes, &BundleChange{
+		Id:       id,
+		Method:   method,
+		Params:   params,
+		Requires: requires,
+	})
+	return id
+}
+
+// placeholder returns the placeholder for the
+// entity created by the change with the given id.
+func placeholder(id string) string {
+	return "$" + id
+}
+
+// series returns the series to use for the given application.
+func (p *changePlanner) series(spec *ApplicationSpec) string {
+	if spec.Series != "" {
+		return spec.Series
+	}
+	return p.bd.Series
+}
+
+// applicationNames returns the names of the applications in order.
+func (bd *BundleData) applicationNames() []string {
+	names := make([]string, 0, len(bd.Applications))
+	for name := range bd.Applications {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// machineIds returns the ids of the machines in numeric order.
+func (bd *BundleData) machineIds() []string {
+	ids := make([]string, 0, len(bd.Machines))
+	for id := range bd.Machines {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		ni, erri := strconv.Atoi(ids[i])
+		nj, errj := strconv.Atoi(ids[j])
+		if erri != nil || errj != nil {
+			return ids[i] < ids[j]
+		}
+		return ni < nj
+	})
+	return ids
+}
+
+func (p *changePlanner) addApplications() {
+	names := p.bd.applicationNames()
+	for _, name := range names {
+		spec := p.bd.Applications[name]
+		if _, ok := p.charmIds[spec.Charm]; !ok {
+			p.charmIds[spec.Charm] = p.add(BundleChangeAddCharm, &AddCharmParams{
+				Charm:  spec.Charm,
+				Series: p.series(spec),
+			})
+		}
+	}
+	for _, name := range names {
+		spec := p.bd.Applications[name]
+		charmId := p.charmIds[spec.Charm]
+		id := p.add(BundleChangeAddApplication, &AddApplicationParams{
+			Charm:            placeholder(charmId),
+			Application:      name,
+			Series:           p.series(spec),
+			Options:          spec.Options,
+			Constraints:      spec.Constraints,
+			Storage:          spec.Storage,
+			Devices:          spec.Devices,
+			EndpointBindings: spec.EndpointBindings,
+			Resources:        spec.Resources,
+			Plan:             spec.Plan,
+		}, charmId)
+		p.appIds[name] = id
+		if len(spec.Annotations) > 0 {
+			p.add(BundleChangeSetAnnotations, &SetAnnotationsParams{
+				Id:          placeholder(id),
+				EntityType:  "application",
+				Annotations: spec.Annotations,
+			}, id)
+		}
+		if spec.Expose {
+			p.add(BundleChangeExpose, &ExposeParams{
+				Application: placeholder(id),
+			}, id)
+		}
+	}
+}
+
+func (p *changePlanner) addMachines() {
+	for _, machineId := range p.bd.machineIds() {
+		params := &AddMachineParams{
+			Series: p.bd.Series,
+		}
+		m := p.bd.Machines[machineId]
+		if m != nil {
+			params.Constraints = m.Constraints
+			if m.Series != "" {
+				params.Series = m.Series
+			}
+		}
+		id := p.add(BundleChangeAddMachine, params)
+		p.machineIds[machineId] = id
+		if m != nil && len(m.Annotations) > 0 {
+			p.add(BundleChangeSetAnnotations, &SetAnnotationsParams{
+				Id:          placeholder(id),
+				EntityType:  "machine",
+				Annotations: m.Annotations,
+			}, id)
+		}
+	}
+}
+
+func (p *changePlanner) addRelations() error {
+	for _, relPair := range p.bd.Relations {
+		if len(relPair) != 2 {
+			return fmt.Errorf("relation %q has %d endpoint(s), not 2", relPair, len(relPair))
+		}
+		var eps [2]endpoint
+		for i, s := range relPair {
+			ep, err := parseEndpoint(s)
+			if err != nil {
+				return err
+			}
+			if _, ok := p.appIds[ep.application]; !ok {
+				return fmt.Errorf("relation %q refers to application %q not defined in this bundle", relPair, ep.application)
+			}
+			eps[i] = ep
+		}
+		if (eps[0].relation == "" || eps[1].relation == "") && p.charms != nil {
+			ep0, ep1, err := inferEndpoints(eps[0], eps[1], p.getCharmMeta)
+			if err != nil {
+				return fmt.Errorf("cannot infer endpoint between %s and %s: %v", eps[0], eps[1], err)
+			}
+			eps = [2]endpoint{ep0, ep1}
+		}
+		id0, id1 := p.appIds[eps[0].application], p.appIds[eps[1].application]
+		p.add(BundleChangeAddRelation, &AddRelationParams{
+			Endpoint1: endpoint{placeholder(id0), eps[0].relation}.String(),
+			Endpoint2: endpoint{placeholder(id1), eps[1].relation}.String(),
+		}, id0, id1)
+	}
+	return nil
+}
+
+func (p *changePlanner) getCharmMeta(appName string) (*Meta, error) {
+	spec := p.bd.Applications[appName]
+	ch, ok := p.charms[spec.Charm]
+	if !ok {
+		return nil, fmt.Errorf("charm %q from application %q not found", spec.Charm, appName)
+	}
+	return ch.Meta(), nil
+}
+
+// addUnits adds the units of all the applications, each after
+// any unit it is placed alongside.
+func (p *changePlanner) addUnits() error {
+	units, err := p.bd.orderedUnits()
+	if err != nil {
+		return err
+	}
+	for _, u := range units {
+		p.addUnit(u)
+	}
+	return nil
+}
+
+func (p *changePlanner) addUnit(u plannedUnit) {
+	appId := p.appIds[u.application]
+	requires := []string{appId}
+	var to string
+	switch up := u.placement; {
+	case up.Application != "":
+		to = p.unitIds[up.unitName()]
+	case up.Machine != "new":
+		to = p.machineIds[up.Machine]
+	}
+	if containerType := u.placement.ContainerType; containerType != "" {
+		params := &AddMachineParams{
+			Series:        p.series(p.bd.Applications[u.application]),
+			ContainerType: containerType,
+		}
+		var parent []string
+		if to != "" {
+			params.ParentId = placeholder(to)
+			parent = []string{to}
+		}
+		to = p.add(BundleChangeAddMachine, params, parent...)
+	}
+	params := &AddUnitParams{
+		Application: placeholder(appId),
+	}
+	if to != "" {
+		params.To = placeholder(to)
+		requires = append(requires, to)
+	}
+	p.unitIds[u.String()] = p.add(BundleChangeAddUnit, params, requires...)
+}
@@ -0,0 +1,327 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package charm_test
+
+import (
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"gopkg.in/juju/charm.v6"
+)
+
+type bundleChangesSuite struct{}
+
+var _ = gc.Suite(&bundleChangesSuite{})
+
+func (*bundleChangesSuite) TestChangePlan(c *gc.C) {
+	bd := readBundle(c, `
+series: xenial
+applications:
+    wordpress:
+        charm: cs:wordpress
+        num_units: 2
+        to: ["0", "lxd:mysql/0"]
+        expose: true
+        options:
+            title: Blog
+        annotations:
+            gui-x: "10"
+    mysql:
+        charm: cs:mysql
+        series: bionic
+        num_units: 1
+        to: ["lxd:new"]
+machines:
+    "0":
+        constraints: mem=2G
+        annotations:
+            rack: "1"
+relations:
+    - ["wordpress", "mysql"]
+`)
+	changes, err := bd.ChangePlan(map[string]charm.Charm{
+		"cs:wordpress": testCharm("wordpress", "|db:mysql"),
+		"cs:mysql":     testCharm("mysql", "server:mysql"),
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(changes, jc.DeepEquals, []*charm.BundleChange{{
+		Id:     "addCharm-0",
+		Method: charm.BundleChangeAddCharm,
+		Params: &charm.AddCharmParams{
+			Charm:  "cs:mysql",
+			Series: "bionic",
+		},
+	}, {
+		Id:     "addCharm-1",
+		Method: charm.BundleChangeAddCharm,
+		Params: &charm.AddCharmParams{
+			Charm:  "cs:wordpress",
+			Series: "xenial",
+		},
+	}, {
+		Id:     "addApplication-2",
+		Method: charm.BundleChangeAddApplication,
+		Params: &charm.AddApplicationParams{
+			Charm:       "$addCharm-0",
+			Application: "mysql",
+			Series:      "bionic",
+		},
+		Requires: []string{"addCharm-0"},
+	}, {
+		Id:     "addApplication-3",
+		Method: charm.BundleChangeAddApplication,
+		Params: &charm.AddApplicationParams{
+			Charm:       "$addCharm-1",
+			Application: "wordpress",
+			Series:      "xenial",
+			Options: map[string]interface{}{
+				"title": "Blog",
+			},
+		},
+		Requires: []string{"addCharm-1"},
+	}, {
+		Id:     "setAnnotations-4",
+		Method: charm.BundleChangeSetAnnotations,
+		Params: &charm.SetAnnotationsParams{
+			Id:          "$addApplication-3",
+			EntityType:  "application",
+			Annotations: map[string]string{"gui-x": "10"},
+		},
+		Requires: []string{"addApplication-3"},
+	}, {
+		Id:     "expose-5",
+		Method: charm.BundleChangeExpose,
+		Params: &charm.ExposeParams{
+			Application: "$addApplication-3",
+		},
+		Requires: []string{"addApplication-3"},
+	}, {
+		Id:     "addMachine-6",
+		Method: charm.BundleChangeAddMachine,
+		Params: &charm.AddMachineParams{
+			Series:      "xenial",
+			Constraints: "mem=2G",
+		},
+	}, {
+		Id:     "setAnnotations-7",
+		Method: charm.BundleChangeSetAnnotations,
+		Params: &charm.SetAnnotationsParams{
+			Id:          "$addMachine-6",
+			EntityType:  "machine",
+			Annotations: map[string]string{"rack": "1"},
+		},
+		Requires: []string{"addMachine-6"},
+	}, {
+		Id:     "addRelation-8",
+		Method: charm.BundleChangeAddRelation,
+		Params: &charm.AddRelationParams{
+			Endpoint1: "$addApplication-3:db",
+			Endpoint2: "$addApplication-2:server",
+		},
+		Requires: []string{"addApplication-3", "addApplication-2"},
+	}, {
+		Id:     "addMachine-9",
+		Method: charm.BundleChangeAddMachine,
+		Params: &charm.AddMachineParams{
+			Series:        "bionic",
+			ContainerType: "lxd",
+		},
+	}, {
+		Id:     "addUnit-10",
+		Method: charm.BundleChangeAddUnit,
+		Params: &charm.AddUnitParams{
+			Application: "$addApplication-2",
+			To:          "$addMachine-9",
+		},
+		Requires: []string{"addApplication-2", "addMachine-9"},
+	}, {
+		Id:     "addUnit-11",
+		Method: charm.BundleChangeAddUnit,
+		Params: &charm.AddUnitParams{
+			Application: "$addApplication-3",
+			To:          "$addMachine-6",
+		},
+		Requires: []string{"addApplication-3", "addMachine-6"},
+	}, {
+		Id:     "addMachine-12",
+		Method: charm.BundleChangeAddMachine,
+		Params: &charm.AddMachineParams{
+			Series:        "xenial",
+			ContainerType: "lxd",
+			ParentId:      "$addUnit-10",
+		},
+		Requires: []string{"addUnit-10"},
+	}, {
+		Id:     "addUnit-13",
+		Method: charm.BundleChangeAddUnit,
+		Params: &charm.AddUnitParams{
+			Application: "$addApplication-3",
+			To:          "$addMachine-12",
+		},
+		Requires: []string{"addApplication-3", "addMachine-12"},
+	}})
+}
+
+func (*bundleChangesSuite) TestChangePlanUnitOrder(c *gc.C) {
+	bd := readBundle(c, `
+applications:
+    a:
+        charm: cs:a
+        num_units: 2
+        to: [b]
+    b:
+        charm: cs:b
+        num_units: 2
+relations:
+    - ["a", "b"]
+`)
+	changes, err := bd.ChangePlan(nil)
+	c.Assert(err, jc.ErrorIsNil)
+	var units []*charm.AddUnitParams
+	for _, change := range changes {
+		switch params := change.Params.(type) {
+		case *charm.AddRelationParams:
+			// Without charms, the relation names are not inferred.
+			c.Assert(params, jc.DeepEquals, &charm.AddRelationParams{
+				Endpoint1: "$addApplication-2",
+				Endpoint2: "$addApplication-3",
+			})
+		case *charm.AddUnitParams:
+			units = append(units, params)
+		}
+	}
+	// The units of b are added first, so that the
+	// units of a can be placed alongside them.
+	c.Assert(units, jc.DeepEquals, []*charm.AddUnitParams{
+		{Application: "$addApplication-3"},
+		{Application: "$addApplication-3"},
+		{Application: "$addApplication-2", To: "$addUnit-5"},
+		{Application: "$addApplication-2", To: "$addUnit-6"},
+	})
+}
+
+var changePlanErrorsTests = []struct {
+	about string
+	data  string
+	err   string
+}{{
+	about: "cyclic placements",
+	data: `
+applications:
+    a:
+        charm: cs:a
+        num_units: 1
+        to: [b/0]
+    b:
+        charm: cs:b
+        num_units: 1
+        to: ["lxd:a/0"]
+`,
+	err: `cycle in placement directives of units a/0, b/0`,
+}, {
+	about: "unknown machine",
+	data: `
+applications:
+    a:
+        charm: cs:a
+        num_units: 1
+        to: ["4"]
+`,
+	err: `unit a/0 is placed on machine "4" not defined in this bundle`,
+}, {
+	about: "unit out of range",
+	data: `
+applications:
+    a:
+        charm: cs:a
+        num_units: 2
+        to: [b]
+    b:
+        charm: cs:b
+        num_units: 1
+`,
+	err: `unit a/1 is placed with unit b/1, greater than the 1 unit\(s\) started by the target application`,
+}, {
+	about: "invalid placement",
+	data: `
+applications:
+    a:
+        charm: cs:a
+        num_units: 1
+        to: ["lxd:"]
+`,
+	err: `application "a": invalid placement syntax "lxd:"`,
+}, {
+	about: "unknown relation application",
+	data: `
+applications:
+    a:
+        charm: cs:a
+relations:
+    - ["a:db", "b:db"]
+`,
+	err: `relation \["a:db" "b:db"\] refers to application "b" not defined in this bundle`,
+}}
+
+func (*bundleChangesSuite) TestChangePlanErrors(c *gc.C) {
+	for i, test := range changePlanErrorsTests {
+		c.Logf("test %d: %s", i, test.about)
+		changes, err := readBundle(c, test.data).ChangePlan(nil)
+		c.Assert(err, gc.ErrorMatches, test.err)
+		c.Assert(changes, gc.IsNil)
+	}
+}
+
+var unitPlacementsTests = []struct {
+	about    string
+	numUnits int
+	to       []string
+	expect   []charm.UnitPlacement
+}{{
+	about:    "no units",
+	numUnits: 0,
+	to:       []string{"0"},
+}, {
+	about:    "no placement",
+	numUnits: 2,
+	expect: []charm.UnitPlacement{
+		{Machine: "new", Unit: -1},
+		{Machine: "new", Unit: -1},
+	},
+}, {
+	about:    "last placement repeated",
+	numUnits: 3,
+	to:       []string{"0", "lxd:new"},
+	expect: []charm.UnitPlacement{
+		{Machine: "0", Unit: -1},
+		{ContainerType: "lxd", Machine: "new", Unit: -1},
+		{ContainerType: "lxd", Machine: "new", Unit: -1},
+	},
+}, {
+	about:    "application units numbered",
+	numUnits: 4,
+	to:       []string{"mysql", "mysql/3", "kvm:mysql"},
+	expect: []charm.UnitPlacement{
+		{Application: "mysql", Unit: 0},
+		{Application: "mysql", Unit: 3},
+		{ContainerType: "kvm", Application: "mysql", Unit: 4},
+		{ContainerType: "kvm", Application: "mysql", Unit: 5},
+	},
+}}
+
+func (*bundleChangesSuite) TestUnitPlacements(c *gc.C) {
+	for i, test := range unitPlacementsTests {
+		c.Logf("test %d: %s", i, test.about)
+		spec := &charm.ApplicationSpec{
+			NumUnits: test.numUnits,
+			To:       test.to,
+		}
+		ups, err := spec.UnitPlacements()
+		c.Assert(err, jc.ErrorIsNil)
+		c.Assert(ups, gc.HasLen, len(test.expect))
+		for j, up := range ups {
+			c.Assert(*up, jc.DeepEquals, test.expect[j])
+		}
+	}
+}
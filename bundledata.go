@@ -120,6 +120,11 @@ type BundleData struct {
 	// unmarshaledWithServices holds whether the original marshaled data held a
 	// legacy "services" field rather than the "applications" field.
 	unmarshaledWithServices bool
+
+	// sources holds the position in the bundle documents of each
+	// item of bundle data read by ReadOverlaidBundleData, indexed
+	// by its slash-separated path such as "applications/mysql".
+	sources map[string]sourcePosition
 }
 
 // UnmarshaledWithServices reports whether the bundle data was
@@ -292,6 +297,12 @@ type bundleDataVerifier struct {
 	verifyConstraints func(c string) error
 	verifyStorage     func(s string) error
 	verifyDevices     func(s string) error
+
+	// item holds the slash-separated path of the bundle item
+	// being verified, such as "applications/mysql", so that
+	// errors can be attributed to its source. It is empty if
+	// errors cannot be attributed to an item.
+	item string
 }
 
 func (verifier *bundleDataVerifier) addErrorf(f string, a ...interface{}) {
@@ -299,6 +310,13 @@ func (verifier *bundleDataVerifier) addErrorf(f string, a ...interface{}) {
 }
 
 func (verifier *bundleDataVerifier) addError(err error) {
+	if pos, ok := verifier.bd.sourceOf(verifier.item); ok {
+		err = &BundleSourceError{
+			Source: pos.source,
+			Line:   pos.line,
+			Err:    err,
+		}
+	}
 	verifier.errors = append(verifier.errors, err)
 }
 
@@ -411,16 +429,23 @@ func (bd *BundleData) verifyBundle(
 		verifier.machineRefCounts[id] = 0
 	}
 	if bd.Series != "" && !IsValidSeries(bd.Series) {
+		verifier.item = "series"
 		verifier.addErrorf("bundle declares an invalid series %q", bd.Series)
 	}
-	verifier.verifyMachines()
-	verifier.verifyApplications()
-	verifier.verifyRelations()
-	verifier.verifyOptions()
-	verifier.verifyEndpointBindings()
+	for _, verify := range []func(){
+		verifier.verifyMachines,
+		verifier.verifyApplications,
+		verifier.verifyRelations,
+		verifier.verifyOptions,
+		verifier.verifyEndpointBindings,
+	} {
+		verifier.item = ""
+		verify()
+	}
 
 	for id, count := range verifier.machineRefCounts {
 		if count == 0 {
+			verifier.item = "machines/" + id
 			verifier.addErrorf("machine %q is not referred to by a placement directive", id)
 		}
 	}
@@ -435,6 +460,7 @@ var (
 
 func (verifier *bundleDataVerifier) verifyMachines() {
 	for id, m := range verifier.bd.Machines {
+		verifier.item = "machines/" + id
 		if !validMachineId.MatchString(id) {
 			verifier.addErrorf("invalid machine id %q found in machines", id)
 		}
@@ -458,6 +484,7 @@ func (verifier *bundleDataVerifier) verifyApplications() {
 		return
 	}
 	for name, svc := range verifier.bd.Applications {
+		verifier.item = "applications/" + name
 		if svc.Charm == "" {
 			verifier.addErrorf("empty charm path")
 		}
@@ -585,7 +612,8 @@ func (verifier *bundleDataVerifier) getCharmMetaForApplication(appName string) (
 
 func (verifier *bundleDataVerifier) verifyRelations() {
 	seen := make(map[[2]endpoint]bool)
-	for _, relPair := range verifier.bd.Relations {
+	for i, relPair := range verifier.bd.Relations {
+		verifier.item = "relations/" + strconv.Itoa(i)
 		if len(relPair) != 2 {
 			verifier.addErrorf("relation %q has %d endpoint(s), not 2", relPair, len(relPair))
 			continue
@@ -652,6 +680,7 @@ func (verifier *bundleDataVerifier) verifyEndpointBindings() {
 		if !ok {
 			continue
 		}
+		verifier.item = "applications/" + name
 		for endpoint, space := range svc.EndpointBindings {
 			_, isInProvides := charm.Meta().Provides[endpoint]
 			_, isInRequires := charm.Meta().Requires[endpoint]
@@ -753,6 +782,7 @@ func (verifier *bundleDataVerifier) verifyOptions() {
 		}
 		config := charm.Config()
 		for name, value := range svc.Options {
+			verifier.item = "applications/" + appName + "/options/" + name
 			opt, ok := config.Options[name]
 			if !ok {
 				verifier.addErrorf("cannot validate application %q: configuration option %q not found in charm %q", appName, name, svc.Charm)
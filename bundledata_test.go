@@ -578,6 +578,14 @@ func (*bundleDataSuite) TestRequiredCharms(c *gc.C) {
 	c.Assert(reqCharms, gc.DeepEquals, []string{"cs:precise/mediawiki-10", "cs:precise/mysql-28"})
 }
 
+// readBundle returns the bundle data parsed from
+// the given YAML, failing the test on error.
+func readBundle(c *gc.C, data string) *charm.BundleData {
+	bd, err := charm.ReadBundleData(strings.NewReader(data))
+	c.Assert(err, jc.ErrorIsNil)
+	return bd
+}
+
 // testCharm returns a charm with the given name
 // and relations. The relations are specified as
 // a string of the form:
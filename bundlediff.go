@@ -0,0 +1,307 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package charm
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// ModelSnapshot describes the applications, units, machines and
+// relations deployed in a model, for comparison with a bundle by
+// BundleData.DiffModel.
+type ModelSnapshot struct {
+	// Applications holds the deployed applications,
+	// indexed by name.
+	Applications map[string]*ModelApplication
+
+	// Machines holds the machines in the model, indexed by id.
+	Machines map[string]*ModelMachine
+
+	// Relations holds the relations in the model, each as a pair
+	// of endpoints of the form "application:relation".
+	Relations [][]string
+}
+
+// ModelApplication describes an application deployed in a model.
+type ModelApplication struct {
+	Charm       string
+	Series      string
+	Constraints string
+	Exposed     bool
+	Options     map[string]interface{}
+	Annotations map[string]string
+
+	// Subordinate holds whether the application is subordinate,
+	// in which case its units are not compared with the bundle.
+	Subordinate bool
+
+	// Units holds the units of the application.
+	Units []ModelUnit
+}
+
+// ModelUnit describes a unit deployed in a model.
+type ModelUnit struct {
+	// Name holds the name of the unit, such as "mysql/0".
+	Name string
+
+	// Machine holds the id of the machine that the unit
+	// is deployed to, or is empty if it is not known.
+	Machine string
+}
+
+// ModelMachine describes a machine in a model.
+type ModelMachine struct {
+	Series      string
+	Constraints string
+	Annotations map[string]string
+}
+
+// ModelSnapshotFromBundle returns a snapshot of the model that deploying
+// the given bundle would produce, with bundle machine ids used as the
+// ids of the machines. It may be used to compare two bundles, such as a
+// bundle and one exported from a model.
+func ModelSnapshotFromBundle(bd *BundleData) *ModelSnapshot {
+	model := &ModelSnapshot{
+		Applications: make(map[string]*ModelApplication),
+		Machines:     make(map[string]*ModelMachine),
+	}
+	for name, spec := range bd.Applications {
+		app := &ModelApplication{
+			Charm:       spec.Charm,
+			Series:      spec.Series,
+			Constraints: spec.Constraints,
+			Exposed:     spec.Expose,
+			Options:     spec.Options,
+			Annotations: spec.Annotations,
+		}
+		// Invalid placements are found by verification;
+		// here they are just left unknown.
+		placements, _ := spec.UnitPlacements()
+		for i := 0; i < spec.NumUnits; i++ {
+			unit := ModelUnit{
+				Name: fmt.Sprintf("%s/%d", name, i),
+			}
+			if i < len(placements) {
+				if up := placements[i]; up.ContainerType == "" && up.Application == "" && up.Machine != "new" {
+					unit.Machine = up.Machine
+				}
+			}
+			app.Units = append(app.Units, unit)
+		}
+		model.Applications[name] = app
+	}
+	for id, m := range bd.Machines {
+		machine := &ModelMachine{}
+		if m != nil {
+			machine.Series = m.Series
+			machine.Constraints = m.Constraints
+			machine.Annotations = m.Annotations
+		}
+		model.Machines[id] = machine
+	}
+	for _, relation := range bd.Relations {
+		model.Relations = append(model.Relations, append([]string(nil), relation...))
+	}
+	return model
+}
+
+// BundleDiff describes the differences between
+// a bundle and a model, as found by BundleData.DiffModel.
+type BundleDiff struct {
+	// ApplicationsToAdd holds the names of the applications in
+	// the bundle that are not in the model, in order.
+	ApplicationsToAdd []string
+
+	// ApplicationsToRemove holds the names of the applications in
+	// the model that are not in the bundle, in order.
+	ApplicationsToRemove []string
+
+	// Applications holds the differences found for each
+	// application that is in both the bundle and the model,
+	// for those applications that differ.
+	Applications map[string]*ApplicationDiff
+
+	// RelationsToAdd holds the relations in the bundle
+	// that are not in the model.
+	RelationsToAdd [][]string
+
+	// RelationsToRemove holds the relations in the model
+	// that are not in the bundle.
+	RelationsToRemove [][]string
+}
+
+// Empty reports whether no differences were found.
+func (d *BundleDiff) Empty() bool {
+	return len(d.ApplicationsToAdd) == 0 &&
+		len(d.ApplicationsToRemove) == 0 &&
+		len(d.Applications) == 0 &&
+		len(d.RelationsToAdd) == 0 &&
+		len(d.RelationsToRemove) == 0
+}
+
+// ApplicationDiff describes the differences between an application
+// in a bundle and the application of the same name in a model. A
+// field is nil if no difference was found for it.
+type ApplicationDiff struct {
+	Charm       *ValueDiff
+	Constraints *ValueDiff
+	Exposed     *ValueDiff
+
+	// NumUnits holds the number of units in the bundle
+	// and the model.
+	NumUnits *ValueDiff
+
+	// Options and Annotations hold the differences in the
+	// options and annotations given by the bundle, indexed by
+	// name. Options and annotations that the bundle does not
+	// give are not compared, as the model may hold defaults
+	// or values set by other tools.
+	Options     map[string]ValueDiff
+	Annotations map[string]ValueDiff
+}
+
+// ValueDiff holds a value that differs between a bundle and
+// a model. A value that is missing from either is nil.
+type ValueDiff struct {
+	Bundle interface{}
+	Model  interface{}
+}
+
+// DiffModel returns the differences between the bundle and the given
+// model: the applications that deploying the bundle would add, those in
+// the model that the bundle does not hold, the differences between the
+// applications in both, and the relations to add and remove. A relation
+// endpoint that does not name a relation matches any relation of its
+// application. Machines are not compared, as bundle machine ids do not
+// identify machines in a model.
+func (bd *BundleData) DiffModel(model *ModelSnapshot) *BundleDiff {
+	diff := &BundleDiff{
+		Applications: make(map[string]*ApplicationDiff),
+	}
+	for _, name := range bd.applicationNames() {
+		app, ok := model.Applications[name]
+		if !ok {
+			diff.ApplicationsToAdd = append(diff.ApplicationsToAdd, name)
+			continue
+		}
+		if appDiff := diffApplication(bd.Applications[name], app); appDiff != nil {
+			diff.Applications[name] = appDiff
+		}
+	}
+	for name := range model.Applications {
+		if _, ok := bd.Applications[name]; !ok {
+			diff.ApplicationsToRemove = append(diff.ApplicationsToRemove, name)
+		}
+	}
+	sort.Strings(diff.ApplicationsToRemove)
+	if len(diff.Applications) == 0 {
+		diff.Applications = nil
+	}
+	for _, relation := range bd.Relations {
+		if !containsRelation(model.Relations, relation) {
+			diff.RelationsToAdd = append(diff.RelationsToAdd, relation)
+		}
+	}
+	for _, relation := range model.Relations {
+		if !containsRelation(bd.Relations, relation) {
+			diff.RelationsToRemove = append(diff.RelationsToRemove, relation)
+		}
+	}
+	return diff
+}
+
+// diffApplication returns the differences between the application
+// spec and the deployed application, or nil if there are none.
+func diffApplication(spec *ApplicationSpec, app *ModelApplication) *ApplicationDiff {
+	diff := &ApplicationDiff{}
+	changed := false
+	diffValue := func(bundle, model interface{}) *ValueDiff {
+		if valuesEqual(bundle, model) {
+			return nil
+		}
+		changed = true
+		return &ValueDiff{bundle, model}
+	}
+	diff.Charm = diffValue(spec.Charm, app.Charm)
+	diff.Constraints = diffValue(spec.Constraints, app.Constraints)
+	diff.Exposed = diffValue(spec.Expose, app.Exposed)
+	if !app.Subordinate {
+		diff.NumUnits = diffValue(spec.NumUnits, len(app.Units))
+	}
+	for name, value := range spec.Options {
+		modelValue, ok := app.Options[name]
+		if ok && valuesEqual(value, modelValue) {
+			continue
+		}
+		if diff.Options == nil {
+			diff.Options = make(map[string]ValueDiff)
+		}
+		diff.Options[name] = ValueDiff{value, modelValue}
+		changed = true
+	}
+	for name, value := range spec.Annotations {
+		modelValue, ok := app.Annotations[name]
+		if ok && value == modelValue {
+			continue
+		}
+		if diff.Annotations == nil {
+			diff.Annotations = make(map[string]ValueDiff)
+		}
+		d := ValueDiff{Bundle: value}
+		if ok {
+			d.Model = modelValue
+		}
+		diff.Annotations[name] = d
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	return diff
+}
+
+// valuesEqual reports whether two values are equal, treating
+// numbers of different types as equal if their values are, so
+// that, for example, an int from YAML equals an int64 or a
+// float64 from JSON.
+func valuesEqual(v1, v2 interface{}) bool {
+	if f1, ok := numberValue(v1); ok {
+		f2, ok := numberValue(v2)
+		return ok && f1 == f2
+	}
+	return reflect.DeepEqual(v1, v2)
+}
+
+func numberValue(v interface{}) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+// containsRelation reports whether relations holds a relation
+// that matches the given one, as by relationMatches, with
+// endpoints that name no relation matching in either.
+func containsRelation(relations [][]string, relation []string) bool {
+	if len(relation) != 2 {
+		return false
+	}
+	for _, r := range relations {
+		if len(r) != 2 {
+			continue
+		}
+		if relationMatches(r, relation) || relationMatches(relation, r) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,151 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package charm_test
+
+import (
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"gopkg.in/juju/charm.v6"
+)
+
+type bundleDiffSuite struct{}
+
+var _ = gc.Suite(&bundleDiffSuite{})
+
+const diffBundle = `
+applications:
+    wordpress:
+        charm: cs:wordpress-4
+        num_units: 2
+        to: ["0"]
+        options:
+            title: Blog
+            skill-level: 3
+        annotations:
+            gui-x: "10"
+    mysql:
+        charm: cs:mysql-1
+        num_units: 1
+    logging:
+        charm: cs:logging
+machines:
+    "0":
+        constraints: mem=2G
+relations:
+    - ["wordpress:db", "mysql:server"]
+    - ["wordpress", "logging"]
+`
+
+func (*bundleDiffSuite) TestModelSnapshotFromBundle(c *gc.C) {
+	bd := readBundle(c, diffBundle)
+	model := charm.ModelSnapshotFromBundle(bd)
+	c.Assert(model.Applications["wordpress"], jc.DeepEquals, &charm.ModelApplication{
+		Charm: "cs:wordpress-4",
+		Options: map[string]interface{}{
+			"title":       "Blog",
+			"skill-level": 3,
+		},
+		Annotations: map[string]string{"gui-x": "10"},
+		Units: []charm.ModelUnit{
+			{Name: "wordpress/0", Machine: "0"},
+			{Name: "wordpress/1", Machine: "0"},
+		},
+	})
+	c.Assert(model.Machines, jc.DeepEquals, map[string]*charm.ModelMachine{
+		"0": {Constraints: "mem=2G"},
+	})
+	c.Assert(model.Relations, jc.DeepEquals, bd.Relations)
+
+	diff := bd.DiffModel(model)
+	c.Assert(diff.Empty(), jc.IsTrue)
+	c.Assert(diff, jc.DeepEquals, &charm.BundleDiff{})
+}
+
+func (*bundleDiffSuite) TestDiffModel(c *gc.C) {
+	bd := readBundle(c, diffBundle)
+	model := &charm.ModelSnapshot{
+		Applications: map[string]*charm.ModelApplication{
+			"wordpress": {
+				Charm:   "cs:wordpress-3",
+				Exposed: true,
+				Options: map[string]interface{}{
+					"title":       "Old blog",
+					"skill-level": int64(3),
+					"debug":       true,
+				},
+				Annotations: map[string]string{
+					"gui-x": "20",
+					"gui-y": "30",
+				},
+				Units: []charm.ModelUnit{{Name: "wordpress/0", Machine: "5"}},
+			},
+			"logging": {
+				Charm:       "cs:logging",
+				Subordinate: true,
+				Units:       []charm.ModelUnit{{Name: "logging/0"}},
+			},
+			"haproxy": {
+				Charm: "cs:haproxy",
+			},
+		},
+		Relations: [][]string{
+			{"logging:info", "wordpress:juju-info"},
+			{"haproxy:reverseproxy", "wordpress:website"},
+		},
+	}
+	diff := bd.DiffModel(model)
+	c.Assert(diff.Empty(), jc.IsFalse)
+	c.Assert(diff, jc.DeepEquals, &charm.BundleDiff{
+		ApplicationsToAdd:    []string{"mysql"},
+		ApplicationsToRemove: []string{"haproxy"},
+		Applications: map[string]*charm.ApplicationDiff{
+			"wordpress": {
+				Charm: &charm.ValueDiff{
+					Bundle: "cs:wordpress-4",
+					Model:  "cs:wordpress-3",
+				},
+				Exposed: &charm.ValueDiff{
+					Bundle: false,
+					Model:  true,
+				},
+				NumUnits: &charm.ValueDiff{
+					Bundle: 2,
+					Model:  1,
+				},
+				Options: map[string]charm.ValueDiff{
+					"title": {Bundle: "Blog", Model: "Old blog"},
+				},
+				Annotations: map[string]charm.ValueDiff{
+					"gui-x": {Bundle: "10", Model: "20"},
+				},
+			},
+		},
+		RelationsToAdd: [][]string{
+			{"wordpress:db", "mysql:server"},
+		},
+		RelationsToRemove: [][]string{
+			{"haproxy:reverseproxy", "wordpress:website"},
+		},
+	})
+}
+
+func (*bundleDiffSuite) TestDiffModelMissingValues(c *gc.C) {
+	bd := readBundle(c, diffBundle)
+	model := charm.ModelSnapshotFromBundle(bd)
+	model.Applications["wordpress"].Options = nil
+	model.Applications["wordpress"].Annotations = nil
+	diff := bd.DiffModel(model)
+	c.Assert(diff.Applications, jc.DeepEquals, map[string]*charm.ApplicationDiff{
+		"wordpress": {
+			Options: map[string]charm.ValueDiff{
+				"title":       {Bundle: "Blog"},
+				"skill-level": {Bundle: 3},
+			},
+			Annotations: map[string]charm.ValueDiff{
+				"gui-x": {Bundle: "10"},
+			},
+		},
+	})
+}
@@ -0,0 +1,119 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package charm
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// The prefixes of option values that include the content of a file.
+const (
+	IncludeFilePrefix   = "include-file://"
+	IncludeBase64Prefix = "include-base64://"
+)
+
+// variablePattern matches an escaped dollar sign or
+// a variable reference in an option value.
+var variablePattern = regexp.MustCompile(`\$\$|\$\{([^}]*)\}`)
+
+var validVariableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ResolveOptions resolves the variable references and include directives
+// in the string option values of the bundle's applications.
+//
+// Each reference of the form ${NAME} is replaced by the value of the
+// named variable in vars; "$$" may be used for a literal dollar sign.
+// A value that then starts with "include-file://" is replaced by the
+// content of the named file, and a value that starts with
+// "include-base64://" by the base64 encoding of the content of the
+// named file. File paths are taken to be relative to bundleDir, the
+// directory containing the bundle file, as for VerifyLocal; absolute
+// paths and paths that lead outside bundleDir are rejected, so that a
+// bundle cannot include arbitrary local files. Included content is not
+// searched for variable references.
+//
+// If any values cannot be resolved, ResolveOptions returns a
+// *VerificationError describing all the problems, and those values
+// are left unchanged.
+func (bd *BundleData) ResolveOptions(bundleDir string, vars map[string]string) error {
+	verifier := &bundleDataVerifier{
+		bundleDir: bundleDir,
+		bd:        bd,
+	}
+	for _, appName := range bd.applicationNames() {
+		for name, value := range bd.Applications[appName].Options {
+			s, ok := value.(string)
+			if !ok {
+				continue
+			}
+			verifier.item = "applications/" + appName + "/options/" + name
+			if s, ok := verifier.resolveOption(appName, name, s, vars); ok {
+				bd.Applications[appName].Options[name] = s
+			}
+		}
+	}
+	return verifier.err()
+}
+
+// resolveOption returns the resolved value of the named option of
+// the given application, and whether it could be resolved.
+func (verifier *bundleDataVerifier) resolveOption(appName, name, value string, vars map[string]string) (string, bool) {
+	ok := true
+	value = variablePattern.ReplaceAllStringFunc(value, func(ref string) string {
+		if ref == "$$" {
+			return "$"
+		}
+		varName := ref[len("${") : len(ref)-1]
+		if !validVariableName.MatchString(varName) {
+			verifier.addErrorf("invalid variable reference %q in option %q of application %q", ref, name, appName)
+			ok = false
+			return ref
+		}
+		varValue, found := vars[varName]
+		if !found {
+			verifier.addErrorf("option %q of application %q refers to undefined variable %q", name, appName, varName)
+			ok = false
+			return ref
+		}
+		return varValue
+	})
+	if !ok {
+		return "", false
+	}
+	var path string
+	var encode bool
+	switch {
+	case strings.HasPrefix(value, IncludeFilePrefix):
+		path = strings.TrimPrefix(value, IncludeFilePrefix)
+	case strings.HasPrefix(value, IncludeBase64Prefix):
+		path, encode = strings.TrimPrefix(value, IncludeBase64Prefix), true
+	default:
+		return value, true
+	}
+	if path == "" {
+		verifier.addErrorf("empty include path in option %q of application %q", name, appName)
+		return "", false
+	}
+	if filepath.IsAbs(path) {
+		verifier.addErrorf("include path %q in option %q of application %q is absolute", path, name, appName)
+		return "", false
+	}
+	if path = filepath.Clean(path); path == ".." || strings.HasPrefix(path, ".."+string(filepath.Separator)) {
+		verifier.addErrorf("include path %q in option %q of application %q refers outside the bundle directory", path, name, appName)
+		return "", false
+	}
+	data, err := ioutil.ReadFile(filepath.Join(verifier.bundleDir, path))
+	if err != nil {
+		verifier.addErrorf("cannot include file in option %q of application %q: %v", name, appName, err)
+		return "", false
+	}
+	if encode {
+		return base64.StdEncoding.EncodeToString(data), true
+	}
+	return string(data), true
+}
@@ -0,0 +1,153 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package charm_test
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"gopkg.in/juju/charm.v6"
+)
+
+type bundleIncludeSuite struct{}
+
+var _ = gc.Suite(&bundleIncludeSuite{})
+
+func (*bundleIncludeSuite) TestResolveOptions(c *gc.C) {
+	bundleDir := c.MkDir()
+	err := ioutil.WriteFile(filepath.Join(bundleDir, "cert.pem"), []byte("-----BEGIN CERTIFICATE-----\n"), 0644)
+	c.Assert(err, jc.ErrorIsNil)
+	err = os.Mkdir(filepath.Join(bundleDir, "scripts"), 0755)
+	c.Assert(err, jc.ErrorIsNil)
+	err = ioutil.WriteFile(filepath.Join(bundleDir, "scripts", "setup.sh"), []byte("#!/bin/sh\necho $1\n"), 0644)
+	c.Assert(err, jc.ErrorIsNil)
+
+	bd := readBundle(c, `
+applications:
+    app:
+        charm: cs:app
+        options:
+            cert: include-file://cert.pem
+            script: include-base64://${SCRIPTS}/setup.sh
+            greeting: Hello ${NAME}, that costs $$5
+            port: 80
+            plain: ${NAME} include-file://cert.pem
+`)
+	err = bd.ResolveOptions(bundleDir, map[string]string{
+		"NAME":    "world",
+		"SCRIPTS": "scripts",
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(bd.Applications["app"].Options, jc.DeepEquals, map[string]interface{}{
+		"cert":     "-----BEGIN CERTIFICATE-----\n",
+		"script":   base64.StdEncoding.EncodeToString([]byte("#!/bin/sh\necho $1\n")),
+		"greeting": "Hello world, that costs $5",
+		"port":     80,
+		"plain":    "world include-file://cert.pem",
+	})
+}
+
+const unresolvedBundle = `
+applications:
+    app:
+        charm: cs:app
+        options:
+            name: ${NAME}
+            user: ${USER}-${HOST}
+            bad: ${1st}
+            cert: include-file://missing.pem
+            key: include-base64://
+`
+
+func (*bundleIncludeSuite) TestResolveOptionsErrors(c *gc.C) {
+	bd := readBundle(c, unresolvedBundle)
+	err := bd.ResolveOptions(c.MkDir(), map[string]string{"NAME": "app"})
+	c.Assert(err, gc.FitsTypeOf, &charm.VerificationError{})
+	var errs []string
+	for _, err := range err.(*charm.VerificationError).Errors {
+		errs = append(errs, err.Error())
+	}
+	sort.Strings(errs)
+	c.Assert(errs, gc.HasLen, 5)
+	c.Assert(errs[0], gc.Matches, `cannot include file in option "cert" of application "app": open .*/missing.pem: no such file or directory`)
+	c.Assert(errs[1:], jc.DeepEquals, []string{
+		`empty include path in option "key" of application "app"`,
+		`invalid variable reference "${1st}" in option "bad" of application "app"`,
+		`option "user" of application "app" refers to undefined variable "HOST"`,
+		`option "user" of application "app" refers to undefined variable "USER"`,
+	})
+	// Only the values that could be resolved are changed.
+	c.Assert(bd.Applications["app"].Options, jc.DeepEquals, map[string]interface{}{
+		"name": "app",
+		"user": "${USER}-${HOST}",
+		"bad":  "${1st}",
+		"cert": "include-file://missing.pem",
+		"key":  "include-base64://",
+	})
+}
+
+func (*bundleIncludeSuite) TestResolveOptionsAttributesErrors(c *gc.C) {
+	bd, err := charm.ReadOverlaidBundleData(charm.BundleDocument{
+		Name: "bundle.yaml",
+		Data: []byte(unresolvedBundle),
+	}, charm.BundleDocument{
+		Name: "overlay.yaml",
+		Data: []byte(`
+applications:
+    app:
+        options:
+            user: admin
+            bad:
+            cert:
+            key:
+`),
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	err = bd.ResolveOptions("", nil)
+	c.Assert(err, gc.ErrorMatches, `bundle.yaml:6: option "name" of application "app" refers to undefined variable "NAME"`)
+	c.Assert(err.(*charm.VerificationError).Errors[0], gc.FitsTypeOf, &charm.BundleSourceError{})
+}
+
+func (*bundleIncludeSuite) TestResolveOptionsOutsideBundleDir(c *gc.C) {
+	dir := c.MkDir()
+	bundleDir := filepath.Join(dir, "bundle")
+	err := os.Mkdir(bundleDir, 0755)
+	c.Assert(err, jc.ErrorIsNil)
+	secret := filepath.Join(dir, "secret")
+	err = ioutil.WriteFile(secret, []byte("top secret"), 0644)
+	c.Assert(err, jc.ErrorIsNil)
+
+	bd := readBundle(c, `
+applications:
+    app:
+        charm: cs:app
+        options:
+            absolute: include-file://${SECRET}
+            parent: include-base64://../secret
+            nested: include-file://sub/../../secret
+`)
+	err = bd.ResolveOptions(bundleDir, map[string]string{"SECRET": secret})
+	c.Assert(err, gc.FitsTypeOf, &charm.VerificationError{})
+	var errs []string
+	for _, err := range err.(*charm.VerificationError).Errors {
+		errs = append(errs, err.Error())
+	}
+	sort.Strings(errs)
+	c.Assert(errs, jc.DeepEquals, []string{
+		`include path "../secret" in option "nested" of application "app" refers outside the bundle directory`,
+		`include path "../secret" in option "parent" of application "app" refers outside the bundle directory`,
+		`include path "` + secret + `" in option "absolute" of application "app" is absolute`,
+	})
+	c.Assert(bd.Applications["app"].Options, jc.DeepEquals, map[string]interface{}{
+		"absolute": "include-file://${SECRET}",
+		"parent":   "include-base64://../secret",
+		"nested":   "include-file://sub/../../secret",
+	})
+}
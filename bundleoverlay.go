@@ -0,0 +1,625 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package charm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// BundleDocument holds the YAML content of a bundle
+// or of a bundle overlay.
+type BundleDocument struct {
+	// Name identifies the document in errors,
+	// typically by the name of its file.
+	Name string
+
+	// Data holds the YAML content of the document.
+	Data []byte
+}
+
+// BundleSourceError holds an error attributed to
+// a line of one of the documents that a bundle was
+// read from by ReadOverlaidBundleData.
+type BundleSourceError struct {
+	// Source holds the name of the document.
+	Source string
+
+	// Line holds the line number in the document,
+	// starting from 1.
+	Line int
+
+	// Err holds the underlying error.
+	Err error
+}
+
+func (err *BundleSourceError) Error() string {
+	return fmt.Sprintf("%s:%d: %v", err.Source, err.Line, err.Err)
+}
+
+// sourcePosition holds the position of an item of
+// bundle data in the document that it came from.
+type sourcePosition struct {
+	source string
+	line   int
+}
+
+// ReadOverlaidBundleData reads bundle data from the base document and
+// then applies each of the overlays in order, as by ApplyOverlay.
+//
+// The returned bundle data records the document and line that each
+// application, option, machine and relation came from, so that the
+// errors returned by its verification methods, such as
+// VerifyWithCharms, are *BundleSourceError values that name them.
+func ReadOverlaidBundleData(base BundleDocument, overlays ...BundleDocument) (*BundleData, error) {
+	bd, err := ReadBundleData(bytes.NewReader(base.Data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", base.Name, err)
+	}
+	bd.sources = make(map[string]sourcePosition)
+	for path, line := range yamlKeyLines(base.Data) {
+		parts := strings.Split(path, "/")
+		switch parts[0] {
+		case "services":
+			path = "applications" + strings.TrimPrefix(path, "services")
+		case "relations":
+			// Only whole relations are tracked, as overlays
+			// add and remove them.
+			if len(parts) > 2 {
+				continue
+			}
+		}
+		bd.sources[path] = sourcePosition{base.Name, line}
+	}
+	for _, overlay := range overlays {
+		if err := bd.ApplyOverlay(overlay); err != nil {
+			return nil, err
+		}
+	}
+	return bd, nil
+}
+
+// ApplyOverlay merges the bundle overlay held in the given document
+// into the bundle data. An overlay has the same form as a bundle, and
+// is merged as follows:
+//
+// - An application whose entry is null is removed, along with any
+// relations that refer to it.
+// - An application that is not in the bundle is added.
+// - For an application that is in the bundle, the options and
+// annotations in the overlay are merged with the existing ones, with
+// a null value removing an option or annotation, and any other field
+// in the overlay replaces the existing one.
+// - A machine in the overlay replaces any machine with the same id,
+// and a machine whose entry is null is removed.
+// - Relations in the overlay are added to the bundle, unless they are
+// already present; the relations in the overlay's "remove-relations"
+// field are removed. An endpoint without a relation name matches all
+// the relations of its application.
+// - The series, description and tags in the overlay replace those in
+// the bundle.
+//
+// If the overlay cannot be applied, the bundle data is left unchanged.
+func (bd *BundleData) ApplyOverlay(overlay BundleDocument) error {
+	var fields map[string]interface{}
+	if err := yaml.Unmarshal(overlay.Data, &fields); err != nil {
+		return fmt.Errorf("%s: cannot unmarshal bundle overlay: %v", overlay.Name, err)
+	}
+	m := &overlayMerger{
+		bd:    bd,
+		doc:   overlay,
+		lines: yamlKeyLines(overlay.Data),
+	}
+	for _, key := range sortedFields(fields) {
+		value := fields[key]
+		var err error
+		switch key {
+		case "applications", "services":
+			err = m.mergeApplications(key, value)
+		case "machines":
+			err = m.mergeMachines(value)
+		case "relations":
+			err = m.addRelations(value)
+		case "remove-relations":
+			err = m.removeRelations(value)
+		case "series":
+			err = m.replace(key, value, &bd.Series)
+		case "description":
+			err = m.replace(key, value, &bd.Description)
+		case "tags":
+			err = m.replace(key, value, &bd.Tags)
+		default:
+			err = m.errorf(key, "unknown field %q", key)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for _, change := range m.changes {
+		change()
+	}
+	return nil
+}
+
+// overlayMerger merges a bundle overlay into bundle data. The overlay
+// is checked in full before any changes are made, so the changes are
+// recorded and only applied when no errors have been found.
+type overlayMerger struct {
+	bd      *BundleData
+	doc     BundleDocument
+	lines   map[string]int
+	changes []func()
+}
+
+// errorf returns an error attributed to the line of the overlay
+// that holds the item with the given path, or to the overlay
+// as a whole if the line is unknown.
+func (m *overlayMerger) errorf(path string, f string, a ...interface{}) error {
+	err := fmt.Errorf(f, a...)
+	if line, ok := m.lines[path]; ok {
+		return &BundleSourceError{
+			Source: m.doc.Name,
+			Line:   line,
+			Err:    err,
+		}
+	}
+	return fmt.Errorf("%s: %v", m.doc.Name, err)
+}
+
+// change records a change to be made to the bundle data.
+func (m *overlayMerger) change(f func()) {
+	m.changes = append(m.changes, f)
+}
+
+// setSources records the overlay as the source of the item at the
+// given path in the overlay, and of all the items within it, which
+// are recorded in the bundle data under the path to.
+func (m *overlayMerger) setSources(from, to string) {
+	sources := m.bd.sources
+	if sources == nil {
+		return
+	}
+	for path, line := range m.lines {
+		if path == from || strings.HasPrefix(path, from+"/") {
+			sources[to+strings.TrimPrefix(path, from)] = sourcePosition{m.doc.Name, line}
+		}
+	}
+}
+
+// removeSources removes the source of the item with the
+// given path, and of all the items within it.
+func (bd *BundleData) removeSources(path string) {
+	for p := range bd.sources {
+		if p == path || strings.HasPrefix(p, path+"/") {
+			delete(bd.sources, p)
+		}
+	}
+}
+
+// sourceOf returns the source of the item with the given
+// path or, if it is not known, of the closest item that
+// encloses it.
+func (bd *BundleData) sourceOf(path string) (sourcePosition, bool) {
+	for path != "" {
+		if pos, ok := bd.sources[path]; ok {
+			return pos, true
+		}
+		i := strings.LastIndex(path, "/")
+		if i < 0 {
+			break
+		}
+		path = path[:i]
+	}
+	return sourcePosition{}, false
+}
+
+func (m *overlayMerger) replace(key string, value interface{}, field interface{}) error {
+	v := reflect.New(reflect.TypeOf(field).Elem())
+	if err := decodeYAMLValue(value, v.Interface()); err != nil {
+		return m.errorf(key, "invalid %s: %v", key, err)
+	}
+	m.change(func() {
+		reflect.ValueOf(field).Elem().Set(v.Elem())
+		m.bd.removeSources(key)
+		m.setSources(key, key)
+	})
+	return nil
+}
+
+// applicationSpecFields and machineSpecFields map
+// the YAML field names of their types to field indexes.
+var (
+	applicationSpecFields = yamlFieldIndexes(reflect.TypeOf(ApplicationSpec{}))
+	machineSpecFields     = yamlFieldIndexes(reflect.TypeOf(MachineSpec{}))
+)
+
+func yamlFieldIndexes(t reflect.Type) map[string]int {
+	fields := make(map[string]int)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fields[name] = i
+	}
+	return fields
+}
+
+// stringMap returns the given YAML mapping with string keys,
+// or an error naming the first key that is not a string.
+func stringMap(value interface{}) (map[string]interface{}, error) {
+	switch value := value.(type) {
+	case nil:
+		return nil, nil
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{})
+		for key, v := range value {
+			s, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("non-string key %v", key)
+			}
+			m[s] = v
+		}
+		return m, nil
+	case map[string]interface{}:
+		return value, nil
+	}
+	return nil, fmt.Errorf("expected mapping, got %T", value)
+}
+
+// sortedFields returns the keys of m in order.
+func sortedFields(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (m *overlayMerger) mergeApplications(key string, value interface{}) error {
+	apps, err := stringMap(value)
+	if err != nil {
+		return m.errorf(key, "invalid %s: %v", key, err)
+	}
+	for _, name := range sortedFields(apps) {
+		if err := m.mergeApplication(key, name, apps[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *overlayMerger) mergeApplication(key, name string, value interface{}) error {
+	path := key + "/" + name
+	target := "applications/" + name
+	if value == nil {
+		m.change(func() {
+			delete(m.bd.Applications, name)
+			m.bd.removeSources(target)
+			m.bd.filterRelations(func(relation []string) bool {
+				for _, ep := range relation {
+					if endpointMatches(ep, name) {
+						return false
+					}
+				}
+				return true
+			})
+		})
+		return nil
+	}
+	fields, err := stringMap(value)
+	if err != nil {
+		return m.errorf(path, "invalid application %q: %v", name, err)
+	}
+	existing := m.bd.Applications[name]
+	spec := &ApplicationSpec{}
+	if existing != nil {
+		*spec = *existing
+		spec.Options = copyOptions(existing.Options)
+		spec.Annotations = copyAnnotations(existing.Annotations)
+	}
+	specValue := reflect.ValueOf(spec).Elem()
+	// replaced records whether any fields of an existing
+	// application are replaced, rather than merged.
+	replaced := false
+	var sources []func()
+	for _, fieldName := range sortedFields(fields) {
+		fieldPath := path + "/" + fieldName
+		fieldTarget := target + "/" + fieldName
+		fieldValue := fields[fieldName]
+		switch fieldName {
+		case "options", "annotations":
+			items, err := stringMap(fieldValue)
+			if err != nil {
+				return m.errorf(fieldPath, "invalid %s in application %q: %v", fieldName, name, err)
+			}
+			if fieldValue == nil {
+				// A null value removes all the items.
+				if fieldName == "options" {
+					spec.Options = nil
+				} else {
+					spec.Annotations = nil
+				}
+				sources = append(sources, func() {
+					m.bd.removeSources(fieldTarget)
+				})
+				continue
+			}
+			for _, item := range sortedFields(items) {
+				itemValue := items[item]
+				if fieldName == "options" {
+					spec.Options = mergeItems(item, spec.Options, itemValue)
+					continue
+				}
+				var s string
+				if err := decodeYAMLValue(itemValue, &s); err != nil {
+					return m.errorf(fieldPath+"/"+item, "invalid annotation %q in application %q: %v", item, name, err)
+				}
+				if itemValue == nil {
+					delete(spec.Annotations, item)
+				} else {
+					if spec.Annotations == nil {
+						spec.Annotations = make(map[string]string)
+					}
+					spec.Annotations[item] = s
+				}
+			}
+			sources = append(sources, func() {
+				for item, itemValue := range items {
+					m.bd.removeSources(fieldTarget + "/" + item)
+					if itemValue != nil {
+						m.setSources(fieldPath+"/"+item, fieldTarget+"/"+item)
+					}
+				}
+			})
+		default:
+			index, ok := applicationSpecFields[fieldName]
+			if !ok {
+				return m.errorf(fieldPath, "unknown field %q in application %q", fieldName, name)
+			}
+			field := specValue.Field(index)
+			v := reflect.New(field.Type())
+			if err := decodeYAMLValue(fieldValue, v.Interface()); err != nil {
+				return m.errorf(fieldPath, "invalid %s in application %q: %v", fieldName, name, err)
+			}
+			field.Set(v.Elem())
+			replaced = true
+			sources = append(sources, func() {
+				m.bd.removeSources(fieldTarget)
+				m.setSources(fieldPath, fieldTarget)
+			})
+		}
+	}
+	m.change(func() {
+		if m.bd.Applications == nil {
+			m.bd.Applications = make(map[string]*ApplicationSpec)
+		}
+		m.bd.Applications[name] = spec
+		if existing == nil {
+			m.bd.removeSources(target)
+			m.setSources(path, target)
+			return
+		}
+		if replaced {
+			if line, ok := m.lines[path]; ok && m.bd.sources != nil {
+				m.bd.sources[target] = sourcePosition{m.doc.Name, line}
+			}
+		}
+		for _, f := range sources {
+			f()
+		}
+	})
+	return nil
+}
+
+// mergeItems sets the named item in the given options, removing
+// it if value is nil, and returns the possibly allocated options.
+func mergeItems(name string, options map[string]interface{}, value interface{}) map[string]interface{} {
+	if value == nil {
+		delete(options, name)
+		return options
+	}
+	if options == nil {
+		options = make(map[string]interface{})
+	}
+	options[name] = value
+	return options
+}
+
+func copyOptions(options map[string]interface{}) map[string]interface{} {
+	if options == nil {
+		return nil
+	}
+	out := make(map[string]interface{})
+	for name, value := range options {
+		out[name] = value
+	}
+	return out
+}
+
+func copyAnnotations(annotations map[string]string) map[string]string {
+	if annotations == nil {
+		return nil
+	}
+	out := make(map[string]string)
+	for name, value := range annotations {
+		out[name] = value
+	}
+	return out
+}
+
+func (m *overlayMerger) mergeMachines(value interface{}) error {
+	machines, err := stringMap(value)
+	if err != nil {
+		return m.errorf("machines", "invalid machines: %v", err)
+	}
+	for _, id := range sortedFields(machines) {
+		id, path := id, "machines/"+id
+		var spec *MachineSpec
+		if machines[id] != nil {
+			fields, err := stringMap(machines[id])
+			if err != nil {
+				return m.errorf(path, "invalid machine %q: %v", id, err)
+			}
+			for _, fieldName := range sortedFields(fields) {
+				if _, ok := machineSpecFields[fieldName]; !ok {
+					return m.errorf(path+"/"+fieldName, "unknown field %q in machine %q", fieldName, id)
+				}
+			}
+			spec = &MachineSpec{}
+			if err := decodeYAMLValue(fields, spec); err != nil {
+				return m.errorf(path, "invalid machine %q: %v", id, err)
+			}
+		}
+		m.change(func() {
+			m.bd.removeSources(path)
+			if spec == nil {
+				delete(m.bd.Machines, id)
+				return
+			}
+			if m.bd.Machines == nil {
+				m.bd.Machines = make(map[string]*MachineSpec)
+			}
+			m.bd.Machines[id] = spec
+			m.setSources(path, path)
+		})
+	}
+	return nil
+}
+
+func (m *overlayMerger) decodeRelations(key string, value interface{}) ([][]string, error) {
+	var relations [][]string
+	if err := decodeYAMLValue(value, &relations); err != nil {
+		return nil, m.errorf(key, "invalid %s: %v", key, err)
+	}
+	for i, relation := range relations {
+		if len(relation) != 2 {
+			return nil, m.errorf(key+"/"+strconv.Itoa(i), "relation %q has %d endpoint(s), not 2", relation, len(relation))
+		}
+	}
+	return relations, nil
+}
+
+func (m *overlayMerger) addRelations(value interface{}) error {
+	relations, err := m.decodeRelations("relations", value)
+	if err != nil {
+		return err
+	}
+	m.change(func() {
+		for i, relation := range relations {
+			if m.bd.hasRelation(relation) {
+				continue
+			}
+			m.setSources("relations/"+strconv.Itoa(i), "relations/"+strconv.Itoa(len(m.bd.Relations)))
+			m.bd.Relations = append(m.bd.Relations, relation)
+		}
+	})
+	return nil
+}
+
+func (m *overlayMerger) removeRelations(value interface{}) error {
+	relations, err := m.decodeRelations("remove-relations", value)
+	if err != nil {
+		return err
+	}
+	m.change(func() {
+		for _, remove := range relations {
+			m.bd.filterRelations(func(relation []string) bool {
+				return !relationMatches(relation, remove)
+			})
+		}
+	})
+	return nil
+}
+
+// hasRelation reports whether the bundle data holds
+// the given relation, with its endpoints in either order.
+func (bd *BundleData) hasRelation(relation []string) bool {
+	for _, r := range bd.Relations {
+		if len(r) == 2 && (r[0] == relation[0] && r[1] == relation[1] || r[0] == relation[1] && r[1] == relation[0]) {
+			return true
+		}
+	}
+	return false
+}
+
+// relationMatches reports whether the given relation is matched by the
+// given pair of endpoints, in either order. An endpoint that names only
+// an application matches any endpoint of that application.
+func relationMatches(relation, pattern []string) bool {
+	if len(relation) != 2 {
+		return false
+	}
+	return endpointMatches(relation[0], pattern[0]) && endpointMatches(relation[1], pattern[1]) ||
+		endpointMatches(relation[0], pattern[1]) && endpointMatches(relation[1], pattern[0])
+}
+
+func endpointMatches(ep, pattern string) bool {
+	if ep == pattern {
+		return true
+	}
+	if strings.Contains(pattern, ":") {
+		return false
+	}
+	return strings.SplitN(ep, ":", 2)[0] == pattern
+}
+
+// filterRelations removes the relations for which keep
+// returns false, renumbering the sources of the others.
+func (bd *BundleData) filterRelations(keep func(relation []string) bool) {
+	var relations [][]string
+	sources := make(map[string]sourcePosition)
+	for i, relation := range bd.Relations {
+		if !keep(relation) {
+			continue
+		}
+		if pos, ok := bd.sources["relations/"+strconv.Itoa(i)]; ok {
+			sources["relations/"+strconv.Itoa(len(relations))] = pos
+		}
+		relations = append(relations, relation)
+	}
+	bd.Relations = relations
+	if bd.sources == nil {
+		return
+	}
+	bd.removeSources("relations")
+	for path, pos := range sources {
+		bd.sources[path] = pos
+	}
+}
+
+// yamlLinePrefix matches the line number given in
+// YAML type errors, which is meaningless when the
+// value has been re-marshaled.
+var yamlLinePrefix = regexp.MustCompile(`^line [0-9]+: `)
+
+// decodeYAMLValue decodes a value unmarshaled from
+// YAML into a generic form into the value pointed to
+// by out.
+func decodeYAMLValue(in, out interface{}) error {
+	data, err := yaml.Marshal(in)
+	if err != nil {
+		return err
+	}
+	if err := yaml.Unmarshal(data, out); err != nil {
+		if typeErr, ok := err.(*yaml.TypeError); ok && len(typeErr.Errors) > 0 {
+			return errors.New(yamlLinePrefix.ReplaceAllString(typeErr.Errors[0], ""))
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,278 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package charm_test
+
+import (
+	"fmt"
+	"sort"
+
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"gopkg.in/juju/charm.v6"
+)
+
+type bundleOverlaySuite struct{}
+
+var _ = gc.Suite(&bundleOverlaySuite{})
+
+const overlayBaseBundle = `
+series: xenial
+applications:
+    wordpress:
+        charm: cs:wordpress
+        num_units: 1
+        to: ["0"]
+        options:
+            title: Blog
+            skill-level: 1
+        annotations:
+            gui-x: "10"
+    mysql:
+        charm: cs:mysql
+        num_units: 1
+        to: ["1"]
+    memcached:
+        charm: cs:memcached
+machines:
+    "0":
+        constraints: mem=2G
+    "1":
+        constraints: mem=4G
+relations:
+    - ["wordpress:db", "mysql:server"]
+    - ["wordpress:cache", "memcached:cache"]
+`
+
+func readOverlaidBundle(c *gc.C, overlays ...string) *charm.BundleData {
+	var docs []charm.BundleDocument
+	for i, overlay := range overlays {
+		docs = append(docs, charm.BundleDocument{
+			Name: []string{"staging.yaml", "prod.yaml"}[i],
+			Data: []byte(overlay),
+		})
+	}
+	bd, err := charm.ReadOverlaidBundleData(charm.BundleDocument{
+		Name: "bundle.yaml",
+		Data: []byte(overlayBaseBundle),
+	}, docs...)
+	c.Assert(err, jc.ErrorIsNil)
+	return bd
+}
+
+func (*bundleOverlaySuite) TestMerge(c *gc.C) {
+	bd := readOverlaidBundle(c, `
+series: bionic
+applications:
+    memcached:
+    wordpress:
+        num_units: 2
+        options:
+            title:
+            skill-level: 3
+        annotations:
+            gui-y: "20"
+    haproxy:
+        charm: cs:haproxy
+        num_units: 1
+machines:
+    "1":
+        series: bionic
+relations:
+    - ["mysql:server", "wordpress:db"]
+    - ["haproxy:reverseproxy", "wordpress:website"]
+`)
+	c.Assert(bd.Series, gc.Equals, "bionic")
+	c.Assert(bd.Applications, jc.DeepEquals, map[string]*charm.ApplicationSpec{
+		"wordpress": {
+			Charm:    "cs:wordpress",
+			NumUnits: 2,
+			To:       []string{"0"},
+			Options: map[string]interface{}{
+				"skill-level": 3,
+			},
+			Annotations: map[string]string{
+				"gui-x": "10",
+				"gui-y": "20",
+			},
+		},
+		"mysql": {
+			Charm:    "cs:mysql",
+			NumUnits: 1,
+			To:       []string{"1"},
+		},
+		"haproxy": {
+			Charm:    "cs:haproxy",
+			NumUnits: 1,
+		},
+	})
+	c.Assert(bd.Machines, jc.DeepEquals, map[string]*charm.MachineSpec{
+		"0": {Constraints: "mem=2G"},
+		"1": {Series: "bionic"},
+	})
+	c.Assert(bd.Relations, jc.DeepEquals, [][]string{
+		{"wordpress:db", "mysql:server"},
+		{"haproxy:reverseproxy", "wordpress:website"},
+	})
+}
+
+func (*bundleOverlaySuite) TestMergeInOrder(c *gc.C) {
+	bd := readOverlaidBundle(c, `
+applications:
+    wordpress:
+        options:
+            title: Staging
+            skill-level:
+`, `
+applications:
+    wordpress:
+        options:
+            title: Production
+        annotations:
+`)
+	c.Assert(bd.Applications["wordpress"].Options, jc.DeepEquals, map[string]interface{}{
+		"title": "Production",
+	})
+	c.Assert(bd.Applications["wordpress"].Annotations, gc.IsNil)
+}
+
+func (*bundleOverlaySuite) TestRemoveRelations(c *gc.C) {
+	bd := readOverlaidBundle(c, `
+remove-relations:
+    - ["mysql", "wordpress"]
+machines:
+    "0":
+`)
+	c.Assert(bd.Relations, jc.DeepEquals, [][]string{
+		{"wordpress:cache", "memcached:cache"},
+	})
+	c.Assert(bd.Machines, jc.DeepEquals, map[string]*charm.MachineSpec{
+		"1": {Constraints: "mem=4G"},
+	})
+
+	bd = readOverlaidBundle(c, `
+remove-relations:
+    - ["memcached:cache", "wordpress:cache"]
+    - ["wordpress:db", "mysql:other"]
+`)
+	c.Assert(bd.Relations, jc.DeepEquals, [][]string{
+		{"wordpress:db", "mysql:server"},
+	})
+}
+
+var applyOverlayErrorsTests = []struct {
+	about   string
+	overlay string
+	err     string
+}{{
+	about:   "invalid YAML",
+	overlay: "applications: [",
+	err:     `overlay.yaml: cannot unmarshal bundle overlay: .*`,
+}, {
+	about:   "unknown field",
+	overlay: "series: bionic\nservers: {}\n",
+	err:     `overlay.yaml:2: unknown field "servers"`,
+}, {
+	about: "unknown application field",
+	overlay: `
+applications:
+    wordpress:
+        colour: red
+`,
+	err: `overlay.yaml:4: unknown field "colour" in application "wordpress"`,
+}, {
+	about: "invalid application field",
+	overlay: `
+series: bionic
+applications:
+    wordpress:
+        num_units: lots
+`,
+	err: `overlay.yaml:5: invalid num_units in application "wordpress": cannot unmarshal !!str ` + "`lots`" + ` into int`,
+}, {
+	about: "invalid options",
+	overlay: `
+applications:
+    wordpress:
+        options: [title]
+`,
+	err: `overlay.yaml:4: invalid options in application "wordpress": expected mapping, got \[\]interface \{\}`,
+}, {
+	about: "unknown machine field",
+	overlay: `
+machines:
+    "0":
+        disks: 2
+`,
+	err: `overlay.yaml:4: unknown field "disks" in machine "0"`,
+}, {
+	about: "invalid relation",
+	overlay: `
+relations:
+    - ["wordpress:db", "mysql:server"]
+    - ["wordpress:db"]
+`,
+	err: `overlay.yaml:4: relation \["wordpress:db"\] has 1 endpoint\(s\), not 2`,
+}}
+
+func (*bundleOverlaySuite) TestApplyOverlayErrors(c *gc.C) {
+	for i, test := range applyOverlayErrorsTests {
+		c.Logf("test %d: %s", i, test.about)
+		bd := readOverlaidBundle(c)
+		err := bd.ApplyOverlay(charm.BundleDocument{
+			Name: "overlay.yaml",
+			Data: []byte(test.overlay),
+		})
+		c.Assert(err, gc.ErrorMatches, test.err)
+		// The bundle data is left unchanged.
+		c.Assert(bd, jc.DeepEquals, readOverlaidBundle(c))
+	}
+}
+
+func (*bundleOverlaySuite) TestVerifyWithCharmsAttributesErrors(c *gc.C) {
+	bd := readOverlaidBundle(c, `
+applications:
+    wordpress:
+        options:
+            title: Staging
+            skill-level: high
+`, `
+applications:
+    memcached:
+        charm: cs:memcached
+        options:
+            colour: red
+relations:
+    - ["wordpress:website", "haproxy:reverseproxy"]
+`)
+	charms := map[string]charm.Charm{
+		"cs:wordpress": testCharm("wordpress", "website:http|db:mysql cache:memcache"),
+		"cs:mysql":     testCharm("mysql", "server:mysql"),
+		"cs:memcached": testCharm("memcached", "cache:memcache"),
+	}
+	err := bd.VerifyWithCharms(nil, nil, nil, charms)
+	c.Assert(err, gc.FitsTypeOf, &charm.VerificationError{})
+	var errs []string
+	for _, err := range err.(*charm.VerificationError).Errors {
+		c.Assert(err, gc.FitsTypeOf, &charm.BundleSourceError{})
+		errs = append(errs, err.Error())
+	}
+	sort.Strings(errs)
+	c.Assert(errs, jc.DeepEquals, []string{
+		`prod.yaml:6: cannot validate application "memcached": configuration option "colour" not found in charm "cs:memcached"`,
+		`prod.yaml:8: relation ["wordpress:website" "haproxy:reverseproxy"] refers to application "haproxy" not defined in this bundle`,
+		`staging.yaml:6: cannot validate application "wordpress": option "skill-level" expected int, got "high"`,
+	})
+}
+
+func (*bundleOverlaySuite) TestVerifyAttributesBaseErrors(c *gc.C) {
+	bd := readOverlaidBundle(c)
+	err := bd.Verify(func(c string) error {
+		if c == "mem=4G" {
+			return fmt.Errorf("too much memory")
+		}
+		return nil
+	}, nil, nil)
+	c.Assert(err, gc.ErrorMatches, `bundle.yaml:22: invalid constraints "mem=4G" in machine "1": too much memory`)
+}
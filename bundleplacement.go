@@ -0,0 +1,227 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package charm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BundleLayout describes where the units of a bundle
+// will be deployed, as returned by BundleData.ResolvePlacements.
+type BundleLayout struct {
+	// Units holds the placement of every unit of every
+	// application, indexed by application name and then
+	// by unit number.
+	Units map[string][]ResolvedPlacement
+
+	// Machines holds the number of machines that will be
+	// created: those declared by the bundle and any new
+	// machines that units or containers are placed on.
+	Machines int
+
+	// Containers holds the number of containers
+	// that will be created.
+	Containers int
+}
+
+// ResolvedPlacement describes the concrete placement of a unit.
+type ResolvedPlacement struct {
+	// Machine holds the id of the machine that hosts the
+	// unit: a machine id from the bundle, or "new-N" for the
+	// Nth new machine, counting from 0.
+	Machine string
+
+	// Container holds the id of the container that holds the
+	// unit, of the form "<machine>/<type>/<n>", or is empty if
+	// the unit is placed directly on the machine.
+	Container string
+}
+
+// ResolvePlacements returns the concrete placement of every unit of every
+// application in the bundle, expanding the placement directives in the
+// To fields as by ApplicationSpec.UnitPlacements. A unit placed alongside
+// another unit shares its machine, or is given a new container on the
+// other unit's machine if a container type is specified. Each "new"
+// placement creates a new machine.
+//
+// ResolvePlacements returns an error if a placement is invalid, refers
+// to a machine or application not defined in the bundle or to a unit
+// beyond the target application's NumUnits, or if units are placed
+// alongside each other in a cycle.
+func (bd *BundleData) ResolvePlacements() (*BundleLayout, error) {
+	units, err := bd.orderedUnits()
+	if err != nil {
+		return nil, err
+	}
+	layout := &BundleLayout{
+		Units:    make(map[string][]ResolvedPlacement),
+		Machines: len(bd.Machines),
+	}
+	resolved := make(map[string]ResolvedPlacement)
+	containers := make(map[string]int)
+	newMachines := 0
+	for _, u := range units {
+		up := u.placement
+		var host string
+		switch {
+		case up.Application != "":
+			target := resolved[up.unitName()]
+			if up.ContainerType == "" {
+				resolved[u.String()] = target
+				continue
+			}
+			host = target.Machine
+		case up.Machine == "new":
+			host = fmt.Sprintf("new-%d", newMachines)
+			newMachines++
+		default:
+			host = up.Machine
+		}
+		placement := ResolvedPlacement{Machine: host}
+		if up.ContainerType != "" {
+			key := host + "/" + up.ContainerType
+			placement.Container = fmt.Sprintf("%s/%d", key, containers[key])
+			containers[key]++
+			layout.Containers++
+		}
+		resolved[u.String()] = placement
+	}
+	layout.Machines += newMachines
+	for _, name := range bd.applicationNames() {
+		n := bd.Applications[name].NumUnits
+		if n <= 0 {
+			continue
+		}
+		placements := make([]ResolvedPlacement, n)
+		for i := range placements {
+			placements[i] = resolved[fmt.Sprintf("%s/%d", name, i)]
+		}
+		layout.Units[name] = placements
+	}
+	return layout, nil
+}
+
+// plannedUnit holds a unit of a bundle application
+// along with its placement.
+type plannedUnit struct {
+	application string
+	unit        int
+	placement   *UnitPlacement
+}
+
+func (u plannedUnit) String() string {
+	return fmt.Sprintf("%s/%d", u.application, u.unit)
+}
+
+// orderedUnits returns the units of all the applications in an order
+// in which they can be deployed: each unit placed alongside another unit
+// comes after it. The units are taken in rounds, in order of application
+// name and unit number, until all of them have been taken.
+func (bd *BundleData) orderedUnits() ([]plannedUnit, error) {
+	placements, err := bd.unitPlacements()
+	if err != nil {
+		return nil, err
+	}
+	var pending []plannedUnit
+	for _, name := range bd.applicationNames() {
+		for i, up := range placements[name] {
+			pending = append(pending, plannedUnit{name, i, up})
+		}
+	}
+	var ordered []plannedUnit
+	done := make(map[string]bool)
+	for len(pending) > 0 {
+		var waiting []plannedUnit
+		for _, u := range pending {
+			if u.placement.Application != "" && !done[u.placement.unitName()] {
+				waiting = append(waiting, u)
+				continue
+			}
+			ordered = append(ordered, u)
+			done[u.String()] = true
+		}
+		if len(waiting) == len(pending) {
+			var names []string
+			for _, u := range waiting {
+				names = append(names, u.String())
+			}
+			return nil, fmt.Errorf("cycle in placement directives of units %s", strings.Join(names, ", "))
+		}
+		pending = waiting
+	}
+	return ordered, nil
+}
+
+// unitName returns the name of the unit that
+// the placement refers to.
+func (up *UnitPlacement) unitName() string {
+	return fmt.Sprintf("%s/%d", up.Application, up.Unit)
+}
+
+// unitPlacements returns the placements of the units of all the
+// applications, as returned by ApplicationSpec.UnitPlacements, checking
+// that they refer to machines and units defined by the bundle.
+func (bd *BundleData) unitPlacements() (map[string][]*UnitPlacement, error) {
+	placements := make(map[string][]*UnitPlacement)
+	for _, name := range bd.applicationNames() {
+		ups, err := bd.Applications[name].UnitPlacements()
+		if err != nil {
+			return nil, fmt.Errorf("application %q: %v", name, err)
+		}
+		for i, up := range ups {
+			switch {
+			case up.Application != "":
+				target, ok := bd.Applications[up.Application]
+				if !ok {
+					return nil, fmt.Errorf("unit %s/%d is placed with application %q not defined in this bundle", name, i, up.Application)
+				}
+				if up.Unit >= target.NumUnits {
+					return nil, fmt.Errorf("unit %s/%d is placed with unit %s, greater than the %d unit(s) started by the target application", name, i, up.unitName(), target.NumUnits)
+				}
+			case up.Machine != "new":
+				if _, ok := bd.Machines[up.Machine]; !ok {
+					return nil, fmt.Errorf("unit %s/%d is placed on machine %q not defined in this bundle", name, i, up.Machine)
+				}
+			}
+		}
+		placements[name] = ups
+	}
+	return placements, nil
+}
+
+// UnitPlacements returns the placement of each of the application's
+// units, expanding the To field as described in its documentation:
+// the last placement directive is repeated for any units beyond those
+// in To, "new" is used for all units if To is empty, and the unit
+// number of a placement that names only an application is resolved.
+// The returned placements are not checked against the bundle.
+func (spec *ApplicationSpec) UnitPlacements() ([]*UnitPlacement, error) {
+	if spec.NumUnits <= 0 {
+		return nil, nil
+	}
+	placements := make([]*UnitPlacement, spec.NumUnits)
+	nextUnit := make(map[string]int)
+	for i := range placements {
+		p := "new"
+		switch {
+		case i < len(spec.To):
+			p = spec.To[i]
+		case len(spec.To) > 0:
+			p = spec.To[len(spec.To)-1]
+		}
+		up, err := ParsePlacement(p)
+		if err != nil {
+			return nil, err
+		}
+		if up.Application != "" {
+			if up.Unit < 0 {
+				up.Unit = nextUnit[up.Application]
+			}
+			nextUnit[up.Application] = up.Unit + 1
+		}
+		placements[i] = up
+	}
+	return placements, nil
+}
@@ -0,0 +1,72 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package charm_test
+
+import (
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"gopkg.in/juju/charm.v6"
+)
+
+type bundlePlacementSuite struct{}
+
+var _ = gc.Suite(&bundlePlacementSuite{})
+
+func (*bundlePlacementSuite) TestResolvePlacements(c *gc.C) {
+	bd := readBundle(c, `
+applications:
+    wordpress:
+        charm: cs:wordpress
+        num_units: 3
+        to: ["0", "lxd:0", "lxd:mysql"]
+    mysql:
+        charm: cs:mysql
+        num_units: 2
+        to: ["new", "kvm:new"]
+    haproxy:
+        charm: cs:haproxy
+        num_units: 3
+        to: [wordpress]
+    logging:
+        charm: cs:logging
+machines:
+    "0":
+    "1":
+`)
+	layout, err := bd.ResolvePlacements()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(layout, jc.DeepEquals, &charm.BundleLayout{
+		Units: map[string][]charm.ResolvedPlacement{
+			"haproxy": {
+				{Machine: "0"},
+				{Machine: "0", Container: "0/lxd/0"},
+				{Machine: "new-0", Container: "new-0/lxd/0"},
+			},
+			"mysql": {
+				{Machine: "new-0"},
+				{Machine: "new-1", Container: "new-1/kvm/0"},
+			},
+			"wordpress": {
+				{Machine: "0"},
+				{Machine: "0", Container: "0/lxd/0"},
+				{Machine: "new-0", Container: "new-0/lxd/0"},
+			},
+		},
+		Machines:   4,
+		Containers: 3,
+	})
+}
+
+func (*bundlePlacementSuite) TestResolvePlacementsErrors(c *gc.C) {
+	for i, test := range changePlanErrorsTests {
+		if test.about == "unknown relation application" {
+			continue
+		}
+		c.Logf("test %d: %s", i, test.about)
+		layout, err := readBundle(c, test.data).ResolvePlacements()
+		c.Assert(err, gc.ErrorMatches, test.err)
+		c.Assert(layout, gc.IsNil)
+	}
+}
@@ -29,6 +29,10 @@ type CharmArchive struct {
 	metrics  *Metrics
 	actions  *Actions
 	revision int
+
+	// spoolPath holds the path of the temporary file
+	// holding the archive, if any. It is removed by Close.
+	spoolPath string
 }
 
 // Trick to ensure *CharmArchive implements the Charm interface.
@@ -61,6 +65,127 @@ func ReadCharmArchiveFromReader(r io.ReaderAt, size int64) (archive *CharmArchiv
 	return readCharmArchive(newZipOpenerFromReader(r, size))
 }
 
+// DefaultStreamMemoryLimit holds the size of the largest archive that
+// ReadCharmArchiveFromStream keeps in memory when no memory limit is
+// specified.
+const DefaultStreamMemoryLimit = 8 * 1024 * 1024
+
+// StreamOptions holds options for ReadCharmArchiveFromStream.
+type StreamOptions struct {
+	// MaxSize holds the maximum size of the archive in bytes.
+	// If it is zero, the size is not limited.
+	MaxSize int64
+
+	// MemoryLimit holds the size of the largest archive that is
+	// kept in memory. Larger archives are spooled to a temporary
+	// file. If it is zero, DefaultStreamMemoryLimit is used.
+	MemoryLimit int64
+
+	// TempDir holds the directory in which to create the
+	// temporary file. If it is empty, the default directory for
+	// temporary files is used.
+	TempDir string
+}
+
+// ReadCharmArchiveFromStream returns a CharmArchive read from r. Unlike
+// ReadCharmArchiveFromReader it does not need to know the size of the
+// archive in advance: small archives are read into memory, and larger
+// ones are spooled to a temporary file.
+//
+// If the archive is larger than opts.MaxSize, an error satisfying
+// IsArchiveTooLarge is returned.
+//
+// The caller should call Close on the returned CharmArchive when it
+// is no longer needed, to remove any temporary file.
+func ReadCharmArchiveFromStream(r io.Reader, opts StreamOptions) (*CharmArchive, error) {
+	memoryLimit := opts.MemoryLimit
+	if memoryLimit <= 0 {
+		memoryLimit = DefaultStreamMemoryLimit
+	}
+	if opts.MaxSize > 0 && opts.MaxSize < memoryLimit {
+		memoryLimit = opts.MaxSize
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(r, memoryLimit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) <= memoryLimit {
+		return ReadCharmArchiveBytes(data)
+	}
+	if opts.MaxSize > 0 && int64(len(data)) > opts.MaxSize {
+		return nil, &archiveTooLargeError{opts.MaxSize}
+	}
+	path, err := spoolArchive(io.MultiReader(bytes.NewReader(data), r), opts)
+	if err != nil {
+		return nil, err
+	}
+	a, err := readCharmArchive(newZipOpenerFromPath(path))
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	a.spoolPath = path
+	return a, nil
+}
+
+// spoolArchive copies the archive read from r to a new temporary
+// file and returns its path.
+func spoolArchive(r io.Reader, opts StreamOptions) (_ string, err error) {
+	f, err := ioutil.TempFile(opts.TempDir, "charm-archive-")
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+	if opts.MaxSize > 0 {
+		r = io.LimitReader(r, opts.MaxSize+1)
+	}
+	n, err := io.Copy(f, r)
+	if err != nil {
+		return "", err
+	}
+	if opts.MaxSize > 0 && n > opts.MaxSize {
+		return "", &archiveTooLargeError{opts.MaxSize}
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// archiveTooLargeError is returned when a charm archive
+// is larger than the permitted maximum size.
+type archiveTooLargeError struct {
+	maxSize int64
+}
+
+func (e *archiveTooLargeError) Error() string {
+	return fmt.Sprintf("charm archive larger than maximum size of %d bytes", e.maxSize)
+}
+
+// IsArchiveTooLarge returns true if err was returned because a
+// charm archive was larger than the permitted maximum size.
+func IsArchiveTooLarge(err error) bool {
+	_, ok := err.(*archiveTooLargeError)
+	return ok
+}
+
+// Close releases any resources held by the archive, removing the
+// temporary file created by ReadCharmArchiveFromStream, if any. The
+// archive must not be used after it has been closed.
+func (a *CharmArchive) Close() error {
+	if a.spoolPath == "" {
+		return nil
+	}
+	path := a.spoolPath
+	a.spoolPath = ""
+	return os.Remove(path)
+}
+
 func readCharmArchive(zopen zipOpener) (archive *CharmArchive, err error) {
 	b := &CharmArchive{
 		zopen: zopen,
@@ -278,6 +403,29 @@ func (a *CharmArchive) ExpandTo(dir string) error {
 	if err := ziputil.ExtractAll(zipr.Reader, dir); err != nil {
 		return err
 	}
+	return a.finishExpand(dir)
+}
+
+// ExpandToWithOptions is like ExpandTo but enforces the limits in
+// opts, and rejects any entry or symbolic link that would lead out
+// of dir, making it suitable for expanding archives from untrusted
+// sources. Limit violations are reported with the error types
+// defined alongside ExpandOptions.
+func (a *CharmArchive) ExpandToWithOptions(dir string, opts ExpandOptions) error {
+	zipr, err := a.zopen.openZip()
+	if err != nil {
+		return err
+	}
+	defer zipr.Close()
+	if err := expandArchive(zipr.Reader, dir, opts); err != nil {
+		return err
+	}
+	return a.finishExpand(dir)
+}
+
+// finishExpand makes sure that the hooks of the charm expanded
+// into dir are executable, and writes its revision file.
+func (a *CharmArchive) finishExpand(dir string) error {
 	hooksDir := filepath.Join(dir, "hooks")
 	fixHook := fixHookFunc(hooksDir, a.meta.Hooks())
 	if err := filepath.Walk(hooksDir, fixHook); err != nil {
@@ -121,6 +121,75 @@ func (s *CharmArchiveSuite) TestReadCharmArchiveFromReader(c *gc.C) {
 	checkDummy(c, archive, "")
 }
 
+func (s *CharmArchiveSuite) TestReadCharmArchiveFromStream(c *gc.C) {
+	data, err := ioutil.ReadFile(s.archivePath)
+	c.Assert(err, gc.IsNil)
+	tempDir := c.MkDir()
+
+	archive, err := charm.ReadCharmArchiveFromStream(bytes.NewReader(data), charm.StreamOptions{
+		TempDir: tempDir,
+	})
+	c.Assert(err, gc.IsNil)
+	checkDummy(c, archive, "")
+	assertDirNames(c, tempDir, []string{})
+	c.Assert(archive.Close(), gc.IsNil)
+}
+
+func (s *CharmArchiveSuite) TestReadCharmArchiveFromStreamSpooled(c *gc.C) {
+	data, err := ioutil.ReadFile(s.archivePath)
+	c.Assert(err, gc.IsNil)
+	tempDir := c.MkDir()
+
+	archive, err := charm.ReadCharmArchiveFromStream(bytes.NewReader(data), charm.StreamOptions{
+		MaxSize:     int64(len(data)),
+		MemoryLimit: 100,
+		TempDir:     tempDir,
+	})
+	c.Assert(err, gc.IsNil)
+	checkDummy(c, archive, "")
+	manifest, err := archive.Manifest()
+	c.Assert(err, gc.IsNil)
+	c.Assert(manifest, jc.DeepEquals, set.NewStrings(dummyManifest...))
+
+	infos, err := ioutil.ReadDir(tempDir)
+	c.Assert(err, gc.IsNil)
+	c.Assert(infos, gc.HasLen, 1)
+	c.Assert(infos[0].Size(), gc.Equals, int64(len(data)))
+
+	c.Assert(archive.Close(), gc.IsNil)
+	assertDirNames(c, tempDir, []string{})
+	c.Assert(archive.Close(), gc.IsNil)
+}
+
+func (s *CharmArchiveSuite) TestReadCharmArchiveFromStreamTooLarge(c *gc.C) {
+	data, err := ioutil.ReadFile(s.archivePath)
+	c.Assert(err, gc.IsNil)
+	tempDir := c.MkDir()
+
+	for _, memoryLimit := range []int64{0, 100} {
+		c.Logf("memory limit %d", memoryLimit)
+		_, err = charm.ReadCharmArchiveFromStream(bytes.NewReader(data), charm.StreamOptions{
+			MaxSize:     int64(len(data)) - 1,
+			MemoryLimit: memoryLimit,
+			TempDir:     tempDir,
+		})
+		c.Assert(err, gc.ErrorMatches, fmt.Sprintf("charm archive larger than maximum size of %d bytes", len(data)-1))
+		c.Assert(charm.IsArchiveTooLarge(err), jc.IsTrue)
+		assertDirNames(c, tempDir, []string{})
+	}
+}
+
+func (s *CharmArchiveSuite) TestReadCharmArchiveFromStreamInvalid(c *gc.C) {
+	tempDir := c.MkDir()
+	_, err := charm.ReadCharmArchiveFromStream(bytes.NewReader(make([]byte, 200)), charm.StreamOptions{
+		MemoryLimit: 100,
+		TempDir:     tempDir,
+	})
+	c.Assert(err, gc.ErrorMatches, "zip: not a valid zip file")
+	c.Assert(charm.IsArchiveTooLarge(err), jc.IsFalse)
+	assertDirNames(c, tempDir, []string{})
+}
+
 func (s *CharmArchiveSuite) TestManifest(c *gc.C) {
 	archive, err := charm.ReadCharmArchive(s.archivePath)
 	c.Assert(err, gc.IsNil)
@@ -382,3 +451,15 @@ func archiveDir(c *gc.C, dirpath string) *charm.CharmArchive {
 	c.Assert(err, gc.IsNil)
 	return archive
 }
+
+// assertDirNames asserts that the given directory
+// holds exactly the given names.
+func assertDirNames(c *gc.C, dir string, names []string) {
+	infos, err := ioutil.ReadDir(dir)
+	c.Assert(err, gc.IsNil)
+	got := []string{}
+	for _, info := range infos {
+		got = append(got, info.Name())
+	}
+	c.Assert(got, jc.DeepEquals, names)
+}
@@ -0,0 +1,350 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package charm
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// ChangeKind describes how an item differs between two charms.
+type ChangeKind string
+
+const (
+	ChangeAdded    ChangeKind = "added"
+	ChangeRemoved  ChangeKind = "removed"
+	ChangeModified ChangeKind = "modified"
+)
+
+// Change describes a single difference in the contract between
+// two revisions of a charm.
+type Change struct {
+	// Kind holds the kind of the change.
+	Kind ChangeKind
+
+	// Path holds the dotted path of the item that changed,
+	// for example "requires.db.interface" or "config.port.type".
+	Path string
+
+	// Breaking holds whether the change may break a deployment
+	// of the old charm when it is upgraded to the new one.
+	Breaking bool
+
+	// Description holds a human readable description of the change.
+	Description string
+}
+
+// String returns the change description, marked when the change
+// is breaking.
+func (c Change) String() string {
+	if c.Breaking {
+		return "breaking: " + c.Description
+	}
+	return c.Description
+}
+
+// CharmDiff holds the differences between two charms,
+// as returned by DiffCharms.
+type CharmDiff struct {
+	Changes []Change
+}
+
+// Breaking reports whether any of the changes is breaking.
+func (d *CharmDiff) Breaking() bool {
+	for _, c := range d.Changes {
+		if c.Breaking {
+			return true
+		}
+	}
+	return false
+}
+
+// DiffCharms compares the contract of the old charm with that of the
+// new one, as seen by the model: relations, config options, actions,
+// storage, devices, resources and the minimum Juju version. Changes are
+// returned grouped in that order, and sorted by name within each group.
+func DiffCharms(old, new Charm) *CharmDiff {
+	d := &charmDiffer{}
+	oldMeta, newMeta := old.Meta(), new.Meta()
+	d.diffRelations("provides", oldMeta.Provides, newMeta.Provides)
+	d.diffRelations("requires", oldMeta.Requires, newMeta.Requires)
+	d.diffRelations("peers", oldMeta.Peers, newMeta.Peers)
+	d.diffConfig(configOptions(old.Config()), configOptions(new.Config()))
+	d.diffActions(actionSpecs(old.Actions()), actionSpecs(new.Actions()))
+	d.diffStorage(oldMeta.Storage, newMeta.Storage)
+	d.diffDevices(oldMeta.Devices, newMeta.Devices)
+	d.diffResources(oldMeta, newMeta)
+	d.diffMinJujuVersion(oldMeta, newMeta)
+	return &CharmDiff{Changes: d.changes}
+}
+
+type charmDiffer struct {
+	changes []Change
+}
+
+func (d *charmDiffer) addf(kind ChangeKind, path string, breaking bool, f string, a ...interface{}) {
+	d.changes = append(d.changes, Change{
+		Kind:        kind,
+		Path:        path,
+		Breaking:    breaking,
+		Description: fmt.Sprintf(f, a...),
+	})
+}
+
+func (d *charmDiffer) diffRelations(section string, old, new map[string]Relation) {
+	for _, name := range unionKeys(old, new) {
+		oldRel, inOld := old[name]
+		newRel, inNew := new[name]
+		path := section + "." + name
+		switch {
+		case !inNew:
+			d.addf(ChangeRemoved, path, true, "relation %q removed from %s", name, section)
+		case !inOld:
+			d.addf(ChangeAdded, path, false, "relation %q added to %s", name, section)
+		default:
+			if oldRel.Interface != newRel.Interface {
+				d.addf(ChangeModified, path+".interface", true, "relation %q interface changed from %q to %q", name, oldRel.Interface, newRel.Interface)
+			}
+			if oldRel.Scope != newRel.Scope {
+				d.addf(ChangeModified, path+".scope", true, "relation %q scope changed from %q to %q", name, oldRel.Scope, newRel.Scope)
+			}
+			if oldRel.Limit != newRel.Limit {
+				// A limit of zero means that the relation is unlimited.
+				breaking := newRel.Limit != 0 && (oldRel.Limit == 0 || newRel.Limit < oldRel.Limit)
+				d.addf(ChangeModified, path+".limit", breaking, "relation %q limit changed from %d to %d", name, oldRel.Limit, newRel.Limit)
+			}
+		}
+	}
+}
+
+func (d *charmDiffer) diffConfig(old, new map[string]Option) {
+	for _, name := range unionKeys(old, new) {
+		oldOpt, inOld := old[name]
+		newOpt, inNew := new[name]
+		path := "config." + name
+		switch {
+		case !inNew:
+			d.addf(ChangeRemoved, path, true, "config option %q removed", name)
+		case !inOld:
+			d.addf(ChangeAdded, path, false, "config option %q added", name)
+		default:
+			if oldOpt.Type != newOpt.Type {
+				d.addf(ChangeModified, path+".type", true, "config option %q type changed from %q to %q", name, oldOpt.Type, newOpt.Type)
+			} else if !reflect.DeepEqual(oldOpt.Default, newOpt.Default) {
+				d.addf(ChangeModified, path+".default", false, "config option %q default changed from %#v to %#v", name, oldOpt.Default, newOpt.Default)
+			}
+		}
+	}
+}
+
+func (d *charmDiffer) diffActions(old, new map[string]ActionSpec) {
+	for _, name := range unionKeys(old, new) {
+		oldSpec, inOld := old[name]
+		newSpec, inNew := new[name]
+		path := "actions." + name
+		switch {
+		case !inNew:
+			d.addf(ChangeRemoved, path, true, "action %q removed", name)
+		case !inOld:
+			d.addf(ChangeAdded, path, false, "action %q added", name)
+		default:
+			d.diffActionParams(name, oldSpec.Params, newSpec.Params)
+		}
+	}
+}
+
+// diffActionParams compares the JSON schemas of the parameters of
+// the named action. Parameters are compared individually; any other
+// change to the schema is reported as a single breaking change, as
+// its effect on existing callers cannot be known.
+func (d *charmDiffer) diffActionParams(action string, old, new map[string]interface{}) {
+	path := "actions." + action + ".params"
+	oldProps, newProps := schemaProperties(old), schemaProperties(new)
+	for _, name := range unionKeys(oldProps, newProps) {
+		oldProp, inOld := oldProps[name]
+		newProp, inNew := newProps[name]
+		switch {
+		case !inNew:
+			d.addf(ChangeRemoved, path+"."+name, true, "action %q parameter %q removed", action, name)
+		case !inOld:
+			d.addf(ChangeAdded, path+"."+name, false, "action %q parameter %q added", action, name)
+		case !reflect.DeepEqual(oldProp, newProp):
+			d.addf(ChangeModified, path+"."+name, true, "action %q parameter %q changed", action, name)
+		}
+	}
+	oldRequired := schemaRequired(old)
+	for _, name := range sortedKeys(schemaRequired(new)) {
+		if !oldRequired[name] {
+			d.addf(ChangeModified, path+".required", true, "action %q parameter %q is now required", action, name)
+		}
+	}
+	if !reflect.DeepEqual(schemaRest(old), schemaRest(new)) {
+		d.addf(ChangeModified, path, true, "action %q parameter schema changed", action)
+	}
+}
+
+func (d *charmDiffer) diffStorage(old, new map[string]Storage) {
+	for _, name := range unionKeys(old, new) {
+		oldStore, inOld := old[name]
+		newStore, inNew := new[name]
+		path := "storage." + name
+		switch {
+		case !inNew:
+			d.addf(ChangeRemoved, path, true, "storage %q removed", name)
+		case !inOld:
+			d.addf(ChangeAdded, path, false, "storage %q added", name)
+		default:
+			if oldStore.Type != newStore.Type {
+				d.addf(ChangeModified, path+".type", true, "storage %q type changed from %q to %q", name, oldStore.Type, newStore.Type)
+			}
+			if oldStore.CountMin != newStore.CountMin {
+				d.addf(ChangeModified, path+".countmin", newStore.CountMin > oldStore.CountMin,
+					"storage %q minimum count changed from %d to %d", name, oldStore.CountMin, newStore.CountMin)
+			}
+			if oldStore.CountMax != newStore.CountMax {
+				d.addf(ChangeModified, path+".countmax", lowerMaximum(int64(oldStore.CountMax), int64(newStore.CountMax)),
+					"storage %q maximum count changed from %d to %d", name, oldStore.CountMax, newStore.CountMax)
+			}
+		}
+	}
+}
+
+func (d *charmDiffer) diffDevices(old, new map[string]Device) {
+	for _, name := range unionKeys(old, new) {
+		oldDevice, inOld := old[name]
+		newDevice, inNew := new[name]
+		path := "devices." + name
+		switch {
+		case !inNew:
+			d.addf(ChangeRemoved, path, true, "device %q removed", name)
+		case !inOld:
+			d.addf(ChangeAdded, path, false, "device %q added", name)
+		default:
+			if oldDevice.Type != newDevice.Type {
+				d.addf(ChangeModified, path+".type", true, "device %q type changed from %q to %q", name, oldDevice.Type, newDevice.Type)
+			}
+			if oldDevice.CountMin != newDevice.CountMin {
+				d.addf(ChangeModified, path+".countmin", newDevice.CountMin > oldDevice.CountMin,
+					"device %q minimum count changed from %d to %d", name, oldDevice.CountMin, newDevice.CountMin)
+			}
+			if oldDevice.CountMax != newDevice.CountMax {
+				d.addf(ChangeModified, path+".countmax", lowerMaximum(oldDevice.CountMax, newDevice.CountMax),
+					"device %q maximum count changed from %d to %d", name, oldDevice.CountMax, newDevice.CountMax)
+			}
+		}
+	}
+}
+
+func (d *charmDiffer) diffResources(oldMeta, newMeta *Meta) {
+	old, new := oldMeta.Resources, newMeta.Resources
+	for _, name := range unionKeys(old, new) {
+		oldRes, inOld := old[name]
+		newRes, inNew := new[name]
+		path := "resources." + name
+		switch {
+		case !inNew:
+			d.addf(ChangeRemoved, path, true, "resource %q removed", name)
+		case !inOld:
+			d.addf(ChangeAdded, path, false, "resource %q added", name)
+		default:
+			if oldRes.Type != newRes.Type {
+				d.addf(ChangeModified, path+".type", true, "resource %q type changed from %q to %q", name, oldRes.Type, newRes.Type)
+			}
+			if oldRes.Path != newRes.Path {
+				d.addf(ChangeModified, path+".filename", false, "resource %q filename changed from %q to %q", name, oldRes.Path, newRes.Path)
+			}
+		}
+	}
+}
+
+func (d *charmDiffer) diffMinJujuVersion(oldMeta, newMeta *Meta) {
+	oldVersion, newVersion := oldMeta.MinJujuVersion, newMeta.MinJujuVersion
+	if oldVersion == newVersion {
+		return
+	}
+	// A raised minimum version may not be satisfied by the
+	// controller that is running the old charm.
+	d.addf(ChangeModified, "min-juju-version", newVersion.Compare(oldVersion) > 0,
+		"minimum Juju version changed from %s to %s", oldVersion, newVersion)
+}
+
+// lowerMaximum reports whether the maximum count newMax is lower
+// than oldMax, where a negative count means that there is no maximum.
+func lowerMaximum(oldMax, newMax int64) bool {
+	if newMax < 0 {
+		return false
+	}
+	return oldMax < 0 || newMax < oldMax
+}
+
+func configOptions(config *Config) map[string]Option {
+	if config == nil {
+		return nil
+	}
+	return config.Options
+}
+
+func actionSpecs(actions *Actions) map[string]ActionSpec {
+	if actions == nil {
+		return nil
+	}
+	return actions.ActionSpecs
+}
+
+// schemaProperties returns the "properties" of the given
+// JSON schema.
+func schemaProperties(schema map[string]interface{}) map[string]interface{} {
+	props, _ := schema["properties"].(map[string]interface{})
+	return props
+}
+
+// schemaRequired returns the set of names in the "required"
+// list of the given JSON schema.
+func schemaRequired(schema map[string]interface{}) map[string]bool {
+	required := make(map[string]bool)
+	names, _ := schema["required"].([]interface{})
+	for _, name := range names {
+		if name, ok := name.(string); ok {
+			required[name] = true
+		}
+	}
+	return required
+}
+
+// schemaRest returns the given JSON schema without the keys that
+// are compared individually by diffActionParams or that do not
+// affect the accepted parameters.
+func schemaRest(schema map[string]interface{}) map[string]interface{} {
+	rest := make(map[string]interface{})
+	for key, value := range schema {
+		switch key {
+		case "properties", "required", "description", "title":
+			continue
+		}
+		rest[key] = value
+	}
+	return rest
+}
+
+// unionKeys returns the sorted union of the keys of the two
+// given maps, which must have string keys.
+func unionKeys(m1, m2 interface{}) []string {
+	keys := make(map[string]bool)
+	for _, m := range []interface{}{m1, m2} {
+		for _, key := range reflect.ValueOf(m).MapKeys() {
+			keys[key.String()] = true
+		}
+	}
+	return sortedKeys(keys)
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
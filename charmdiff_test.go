@@ -0,0 +1,365 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package charm_test
+
+import (
+	"strings"
+
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"gopkg.in/juju/charm.v6"
+)
+
+type CharmDiffSuite struct{}
+
+var _ = gc.Suite(&CharmDiffSuite{})
+
+// yamlCharm implements charm.Charm with data parsed
+// from YAML documents.
+type yamlCharm struct {
+	meta    *charm.Meta
+	config  *charm.Config
+	actions *charm.Actions
+}
+
+func newYAMLCharm(c *gc.C, meta, config, actions string) *yamlCharm {
+	ch := &yamlCharm{
+		config:  charm.NewConfig(),
+		actions: charm.NewActions(),
+	}
+	var err error
+	ch.meta, err = charm.ReadMeta(strings.NewReader(meta))
+	c.Assert(err, jc.ErrorIsNil)
+	if config != "" {
+		ch.config, err = charm.ReadConfig(strings.NewReader(config))
+		c.Assert(err, jc.ErrorIsNil)
+	}
+	if actions != "" {
+		ch.actions, err = charm.ReadActionsYaml(strings.NewReader(actions))
+		c.Assert(err, jc.ErrorIsNil)
+	}
+	return ch
+}
+
+func (ch *yamlCharm) Meta() *charm.Meta       { return ch.meta }
+func (ch *yamlCharm) Config() *charm.Config   { return ch.config }
+func (ch *yamlCharm) Metrics() *charm.Metrics { return nil }
+func (ch *yamlCharm) Actions() *charm.Actions { return ch.actions }
+func (ch *yamlCharm) Revision() int           { return 0 }
+
+func (s *CharmDiffSuite) TestNoChanges(c *gc.C) {
+	ch := readCharmDir(c, "dummy")
+	diff := charm.DiffCharms(ch, ch)
+	c.Assert(diff.Changes, gc.HasLen, 0)
+	c.Assert(diff.Breaking(), jc.IsFalse)
+}
+
+func (s *CharmDiffSuite) TestRelations(c *gc.C) {
+	old := newYAMLCharm(c, `
+name: foo
+summary: foo
+description: foo
+provides:
+  website: http
+  gone: thing
+  cache: memcache
+requires:
+  db:
+    interface: mysql
+    limit: 1
+  logs:
+    interface: syslog
+    scope: container
+peers:
+  ring: cluster
+`, "", "")
+	new := newYAMLCharm(c, `
+name: foo
+summary: foo
+description: foo
+provides:
+  website: http
+  metrics: prometheus
+  cache:
+    interface: memcache
+    limit: 3
+requires:
+  db:
+    interface: pgsql
+    limit: 2
+  logs: syslog
+peers:
+  ring: cluster
+`, "", "")
+	diff := charm.DiffCharms(old, new)
+	c.Assert(diff.Changes, jc.DeepEquals, []charm.Change{{
+		Kind:        charm.ChangeModified,
+		Path:        "provides.cache.limit",
+		Breaking:    true,
+		Description: `relation "cache" limit changed from 0 to 3`,
+	}, {
+		Kind:        charm.ChangeRemoved,
+		Path:        "provides.gone",
+		Breaking:    true,
+		Description: `relation "gone" removed from provides`,
+	}, {
+		Kind:        charm.ChangeAdded,
+		Path:        "provides.metrics",
+		Description: `relation "metrics" added to provides`,
+	}, {
+		Kind:        charm.ChangeModified,
+		Path:        "requires.db.interface",
+		Breaking:    true,
+		Description: `relation "db" interface changed from "mysql" to "pgsql"`,
+	}, {
+		Kind:        charm.ChangeModified,
+		Path:        "requires.db.limit",
+		Description: `relation "db" limit changed from 1 to 2`,
+	}, {
+		Kind:        charm.ChangeModified,
+		Path:        "requires.logs.scope",
+		Breaking:    true,
+		Description: `relation "logs" scope changed from "container" to "global"`,
+	}})
+	c.Assert(diff.Breaking(), jc.IsTrue)
+}
+
+const diffMeta = `
+name: foo
+summary: foo
+description: foo
+`
+
+func (s *CharmDiffSuite) TestConfig(c *gc.C) {
+	old := newYAMLCharm(c, diffMeta, `
+options:
+  port: {type: int, default: 80}
+  name: {type: string, default: foo}
+  debug: {type: boolean}
+`, "")
+	new := newYAMLCharm(c, diffMeta, `
+options:
+  port: {type: string, default: "80"}
+  name: {type: string, default: bar}
+  verbose: {type: boolean}
+`, "")
+	diff := charm.DiffCharms(old, new)
+	c.Assert(diff.Changes, jc.DeepEquals, []charm.Change{{
+		Kind:        charm.ChangeRemoved,
+		Path:        "config.debug",
+		Breaking:    true,
+		Description: `config option "debug" removed`,
+	}, {
+		Kind:        charm.ChangeModified,
+		Path:        "config.name.default",
+		Description: `config option "name" default changed from "foo" to "bar"`,
+	}, {
+		Kind:        charm.ChangeModified,
+		Path:        "config.port.type",
+		Breaking:    true,
+		Description: `config option "port" type changed from "int" to "string"`,
+	}, {
+		Kind:        charm.ChangeAdded,
+		Path:        "config.verbose",
+		Description: `config option "verbose" added`,
+	}})
+}
+
+func (s *CharmDiffSuite) TestActions(c *gc.C) {
+	old := newYAMLCharm(c, diffMeta, "", `
+snapshot:
+  description: Take a snapshot.
+  params:
+    outfile:
+      type: string
+    quality:
+      type: integer
+    compress:
+      type: boolean
+restart:
+  description: Restart.
+`)
+	new := newYAMLCharm(c, diffMeta, "", `
+snapshot:
+  description: Take a better snapshot.
+  params:
+    outfile:
+      type: string
+    quality:
+      type: string
+    format:
+      type: string
+  required: [outfile]
+  additionalProperties: false
+backup:
+  description: Back up.
+`)
+	diff := charm.DiffCharms(old, new)
+	c.Assert(diff.Changes, jc.DeepEquals, []charm.Change{{
+		Kind:        charm.ChangeAdded,
+		Path:        "actions.backup",
+		Description: `action "backup" added`,
+	}, {
+		Kind:        charm.ChangeRemoved,
+		Path:        "actions.restart",
+		Breaking:    true,
+		Description: `action "restart" removed`,
+	}, {
+		Kind:        charm.ChangeRemoved,
+		Path:        "actions.snapshot.params.compress",
+		Breaking:    true,
+		Description: `action "snapshot" parameter "compress" removed`,
+	}, {
+		Kind:        charm.ChangeAdded,
+		Path:        "actions.snapshot.params.format",
+		Description: `action "snapshot" parameter "format" added`,
+	}, {
+		Kind:        charm.ChangeModified,
+		Path:        "actions.snapshot.params.quality",
+		Breaking:    true,
+		Description: `action "snapshot" parameter "quality" changed`,
+	}, {
+		Kind:        charm.ChangeModified,
+		Path:        "actions.snapshot.params.required",
+		Breaking:    true,
+		Description: `action "snapshot" parameter "outfile" is now required`,
+	}, {
+		Kind:        charm.ChangeModified,
+		Path:        "actions.snapshot.params",
+		Breaking:    true,
+		Description: `action "snapshot" parameter schema changed`,
+	}})
+}
+
+func (s *CharmDiffSuite) TestStorageAndDevices(c *gc.C) {
+	old := newYAMLCharm(c, `
+name: foo
+summary: foo
+description: foo
+storage:
+  data:
+    type: filesystem
+    multiple:
+      range: 1-4
+  logs:
+    type: filesystem
+  cache:
+    type: block
+devices:
+  gpu:
+    type: gpu
+    countmin: 1
+    countmax: 2
+`, "", "")
+	new := newYAMLCharm(c, `
+name: foo
+summary: foo
+description: foo
+storage:
+  data:
+    type: filesystem
+    multiple:
+      range: 2-
+  logs:
+    type: block
+devices:
+  gpu:
+    type: gpu
+    countmin: 0
+    countmax: 1
+  tpu:
+    type: tpu
+`, "", "")
+	diff := charm.DiffCharms(old, new)
+	c.Assert(diff.Changes, jc.DeepEquals, []charm.Change{{
+		Kind:        charm.ChangeRemoved,
+		Path:        "storage.cache",
+		Breaking:    true,
+		Description: `storage "cache" removed`,
+	}, {
+		Kind:        charm.ChangeModified,
+		Path:        "storage.data.countmin",
+		Breaking:    true,
+		Description: `storage "data" minimum count changed from 1 to 2`,
+	}, {
+		Kind:        charm.ChangeModified,
+		Path:        "storage.data.countmax",
+		Description: `storage "data" maximum count changed from 4 to -1`,
+	}, {
+		Kind:        charm.ChangeModified,
+		Path:        "storage.logs.type",
+		Breaking:    true,
+		Description: `storage "logs" type changed from "filesystem" to "block"`,
+	}, {
+		Kind:        charm.ChangeModified,
+		Path:        "devices.gpu.countmin",
+		Description: `device "gpu" minimum count changed from 1 to 0`,
+	}, {
+		Kind:        charm.ChangeModified,
+		Path:        "devices.gpu.countmax",
+		Breaking:    true,
+		Description: `device "gpu" maximum count changed from 2 to 1`,
+	}, {
+		Kind:        charm.ChangeAdded,
+		Path:        "devices.tpu",
+		Description: `device "tpu" added`,
+	}})
+}
+
+func (s *CharmDiffSuite) TestResourcesAndMinJujuVersion(c *gc.C) {
+	old := newYAMLCharm(c, `
+name: foo
+summary: foo
+description: foo
+min-juju-version: 2.0.0
+resources:
+  blob:
+    type: file
+    filename: blob.tgz
+  gone:
+    type: file
+    filename: gone.tgz
+`, "", "")
+	new := newYAMLCharm(c, `
+name: foo
+summary: foo
+description: foo
+min-juju-version: 2.4.0
+resources:
+  blob:
+    type: file
+    filename: blob.tar.gz
+`, "", "")
+	diff := charm.DiffCharms(old, new)
+	c.Assert(diff.Changes, jc.DeepEquals, []charm.Change{{
+		Kind:        charm.ChangeModified,
+		Path:        "resources.blob.filename",
+		Description: `resource "blob" filename changed from "blob.tgz" to "blob.tar.gz"`,
+	}, {
+		Kind:        charm.ChangeRemoved,
+		Path:        "resources.gone",
+		Breaking:    true,
+		Description: `resource "gone" removed`,
+	}, {
+		Kind:        charm.ChangeModified,
+		Path:        "min-juju-version",
+		Breaking:    true,
+		Description: `minimum Juju version changed from 2.0.0 to 2.4.0`,
+	}})
+
+	diff = charm.DiffCharms(new, old)
+	c.Assert(diff.Changes[2], jc.DeepEquals, charm.Change{
+		Kind:        charm.ChangeModified,
+		Path:        "min-juju-version",
+		Description: `minimum Juju version changed from 2.4.0 to 2.0.0`,
+	})
+}
+
+func (s *CharmDiffSuite) TestChangeString(c *gc.C) {
+	change := charm.Change{Description: "foo", Breaking: true}
+	c.Assert(change.String(), gc.Equals, "breaking: foo")
+	change.Breaking = false
+	c.Assert(change.String(), gc.Equals, "foo")
+}
@@ -5,15 +5,18 @@ package charm
 
 import (
 	"archive/zip"
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
-	"syscall"
+	"time"
 )
 
 // The CharmDir type encapsulates access to data and operations
@@ -170,9 +173,41 @@ func resolveSymlinkedRoot(rootPath string) (string, error) {
 	return rootPath, nil
 }
 
+// ArchiveOptions holds options for writing a charm archive.
+type ArchiveOptions struct {
+	// Reproducible specifies that the archive should depend only
+	// on the content of the charm, so that building it from the same
+	// source always produces the same bytes. Entries are written in
+	// sorted order, with a fixed modification time and with
+	// permissions normalized to 0755 for directories and executable
+	// files, 0644 for other files and 0777 for symbolic links.
+	Reproducible bool
+}
+
+// ReproducibleModTime holds the modification time given to all
+// entries of a reproducible archive. It is the earliest time that
+// can be represented in a zip file.
+var ReproducibleModTime = time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
+
 // ArchiveTo creates a charm file from the charm expanded in dir.
 // By convention a charm archive should have a ".charm" suffix.
 func (dir *CharmDir) ArchiveTo(w io.Writer) error {
+	return dir.ArchiveToWithOptions(w, ArchiveOptions{})
+}
+
+// ArchiveToWithOptions is like ArchiveTo but allows the
+// archive to be tailored with the given options.
+func (dir *CharmDir) ArchiveToWithOptions(w io.Writer, opts ArchiveOptions) error {
+	entries, err := dir.archiveEntries()
+	if err != nil {
+		return err
+	}
+	return writeArchiveEntries(w, entries, opts)
+}
+
+// archiveEntries returns the entries that are
+// written to an archive of the charm.
+func (dir *CharmDir) archiveEntries() ([]archiveEntry, error) {
 	versionString, vcsType, err := dir.MaybeGenerateVersionString()
 	if err != nil {
 		// Just to be safe, ensure version is "" on error.
@@ -181,33 +216,155 @@ func (dir *CharmDir) ArchiveTo(w io.Writer) error {
 			"%q version string generation failed : %v\nThis means that the charm version won't show in juju status.",
 			vcsType, err)
 	}
-	return writeArchive(w, dir.Path, dir.revision, versionString, dir.Meta().Hooks())
+	ignore, err := readIgnoreFile(dir.Path)
+	if err != nil {
+		return nil, err
+	}
+	return collectArchiveEntries(dir.Path, dir.revision, versionString, dir.Meta().Hooks(), ignore)
+}
+
+// PreviewArchive returns the names of the entries that ArchiveTo
+// would write, in sorted order, without writing an archive. Directory
+// names end with a slash; the root directory is not included. Paths
+// matched by the charm's .charmignore file are excluded, as are
+// hidden files and the top level build directory.
+func (dir *CharmDir) PreviewArchive() ([]string, error) {
+	entries, err := dir.archiveEntries()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.name != "./" {
+			names = append(names, e.name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
 }
 
 func writeArchive(w io.Writer, path string, revision int, versionString string, hooks map[string]bool) error {
-	zipw := zip.NewWriter(w)
-	defer zipw.Close()
+	entries, err := collectArchiveEntries(path, revision, versionString, hooks, nil)
+	if err != nil {
+		return err
+	}
+	return writeArchiveEntries(w, entries, ArchiveOptions{})
+}
+
+// archiveEntry describes a single entry in an archive.
+type archiveEntry struct {
+	// name holds the slash-separated path of the entry.
+	// Directory names end with a slash.
+	name string
+
+	// mode holds the type and permissions of the entry.
+	mode os.FileMode
 
+	// path holds the file holding the content of a regular
+	// file entry. It is empty for generated files.
+	path string
+
+	// data holds the content of generated files and
+	// the targets of symbolic links.
+	data []byte
+}
+
+// open returns a reader for the content of the entry.
+func (e *archiveEntry) open() (io.ReadCloser, error) {
+	if e.path == "" {
+		return ioutil.NopCloser(bytes.NewReader(e.data)), nil
+	}
+	return os.Open(e.path)
+}
+
+// collectArchiveEntries returns the entries to write to an
+// archive of the charm or bundle directory at path, leaving
+// out any paths excluded by ignore, which may be nil.
+func collectArchiveEntries(path string, revision int, versionString string, hooks map[string]bool, ignore *ignoreMatcher) ([]archiveEntry, error) {
 	// The root directory may be symlinked elsewhere so
-	// resolve that before creating the zip.
+	// resolve that before collecting the entries.
 	rootPath, err := resolveSymlinkedRoot(path)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	zp := zipPacker{zipw, rootPath, hooks}
+	zp := zipPacker{root: rootPath, hooks: hooks, ignore: ignore}
 	if revision != -1 {
 		zp.AddFile("revision", strconv.Itoa(revision))
 	}
 	if versionString != "" {
 		zp.AddFile("version", versionString)
 	}
-	return filepath.Walk(rootPath, zp.WalkFunc())
+	if err := filepath.Walk(rootPath, zp.WalkFunc()); err != nil {
+		return nil, err
+	}
+	return zp.entries, nil
+}
+
+// writeArchiveEntries writes a zip archive holding the given
+// entries to w.
+func writeArchiveEntries(w io.Writer, entries []archiveEntry, opts ArchiveOptions) error {
+	if opts.Reproducible {
+		entries = append([]archiveEntry(nil), entries...)
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].name < entries[j].name
+		})
+	}
+	zipw := zip.NewWriter(w)
+	for i := range entries {
+		if err := writeArchiveEntry(zipw, &entries[i], opts); err != nil {
+			zipw.Close()
+			return err
+		}
+	}
+	return zipw.Close()
+}
+
+func writeArchiveEntry(zipw *zip.Writer, e *archiveEntry, opts ArchiveOptions) error {
+	h := &zip.FileHeader{
+		Name:   e.name,
+		Method: zip.Deflate,
+	}
+	mode := e.mode
+	if mode.IsDir() || mode&os.ModeSymlink != 0 {
+		h.Method = zip.Store
+	}
+	if opts.Reproducible {
+		mode = normalizedMode(mode)
+		h.Modified = ReproducibleModTime
+	}
+	h.SetMode(mode)
+	w, err := zipw.CreateHeader(h)
+	if err != nil || mode.IsDir() {
+		return err
+	}
+	r, err := e.open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	_, err = io.Copy(w, r)
+	return err
+}
+
+// normalizedMode returns the mode given to an
+// entry with the given mode in a reproducible archive.
+func normalizedMode(mode os.FileMode) os.FileMode {
+	switch {
+	case mode.IsDir():
+		return os.ModeDir | 0755
+	case mode&os.ModeSymlink != 0:
+		return os.ModeSymlink | 0777
+	case mode&0111 != 0:
+		return 0755
+	}
+	return 0644
 }
 
 type zipPacker struct {
-	*zip.Writer
-	root  string
-	hooks map[string]bool
+	root    string
+	hooks   map[string]bool
+	ignore  *ignoreMatcher
+	entries []archiveEntry
 }
 
 func (zp *zipPacker) WalkFunc() filepath.WalkFunc {
@@ -216,14 +373,12 @@ func (zp *zipPacker) WalkFunc() filepath.WalkFunc {
 	}
 }
 
-func (zp *zipPacker) AddFile(filename string, value string) error {
-	h := &zip.FileHeader{Name: filename}
-	h.SetMode(syscall.S_IFREG | 0644)
-	w, err := zp.CreateHeader(h)
-	if err == nil {
-		_, err = w.Write([]byte(value))
-	}
-	return err
+func (zp *zipPacker) AddFile(filename string, value string) {
+	zp.entries = append(zp.entries, archiveEntry{
+		name: filename,
+		mode: 0644,
+		data: []byte(value),
+	})
 }
 
 func (zp *zipPacker) visit(path string, fi os.FileInfo, err error) error {
@@ -238,32 +393,30 @@ func (zp *zipPacker) visit(path string, fi os.FileInfo, err error) error {
 	// zip file spec 4.4.17.1 says that separators are always "/" even on Windows.
 	relpath = filepath.ToSlash(relpath)
 
-	method := zip.Deflate
 	hidden := len(relpath) > 1 && relpath[0] == '.'
+	ignored := relpath != "." && zp.ignore.match(relpath, fi.IsDir())
 	if fi.IsDir() {
 		if relpath == "build" {
 			return filepath.SkipDir
 		}
-		if hidden {
+		if hidden || ignored {
 			return filepath.SkipDir
 		}
 		relpath += "/"
-		method = zip.Store
+	}
+	if ignored {
+		return nil
 	}
 
 	mode := fi.Mode()
 	if err := checkFileType(relpath, mode); err != nil {
 		return err
 	}
-	if mode&os.ModeSymlink != 0 {
-		method = zip.Store
-	}
 	if hidden || relpath == "revision" {
 		return nil
 	}
-	h := &zip.FileHeader{
-		Name:   relpath,
-		Method: method,
+	e := archiveEntry{
+		name: relpath,
 	}
 
 	perm := os.FileMode(0644)
@@ -279,14 +432,11 @@ func (zp *zipPacker) visit(path string, fi os.FileInfo, err error) error {
 			perm = perm | 0100
 		}
 	}
-	h.SetMode(mode&^0777 | perm)
+	e.mode = mode&^0777 | perm
 
-	w, err := zp.CreateHeader(h)
-	if err != nil || fi.IsDir() {
-		return err
-	}
-	var data []byte
-	if mode&os.ModeSymlink != 0 {
+	switch {
+	case fi.IsDir():
+	case mode&os.ModeSymlink != 0:
 		target, err := os.Readlink(path)
 		if err != nil {
 			return err
@@ -294,17 +444,12 @@ func (zp *zipPacker) visit(path string, fi os.FileInfo, err error) error {
 		if err := checkSymlinkTarget(zp.root, relpath, target); err != nil {
 			return err
 		}
-		data = []byte(target)
-		_, err = w.Write(data)
-	} else {
-		file, err := os.Open(path)
-		if err != nil {
-			return err
-		}
-		defer file.Close()
-		_, err = io.Copy(w, file)
+		e.data = []byte(target)
+	default:
+		e.path = path
 	}
-	return err
+	zp.entries = append(zp.entries, e)
+	return nil
 }
 
 func checkSymlinkTarget(basedir, symlink, target string) error {
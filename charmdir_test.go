@@ -12,6 +12,7 @@ import (
 	"path/filepath"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/juju/loggo"
 	"github.com/juju/testing"
@@ -371,6 +372,52 @@ func (s *CharmDirSuite) TestArchiveToWithBadType(c *gc.C) {
 	c.Assert(err, gc.ErrorMatches, `file is a named pipe: "hooks/badfile"`)
 }
 
+func (s *CharmDirSuite) TestArchiveToReproducible(c *gc.C) {
+	charmDir1 := cloneDir(c, charmDirPath(c, "dummy"))
+	charmDir2 := cloneDir(c, charmDirPath(c, "dummy"))
+
+	// Make the second copy differ in everything but content.
+	err := os.Chmod(filepath.Join(charmDir2, "config.yaml"), 0600)
+	c.Assert(err, gc.IsNil)
+	err = os.Chmod(filepath.Join(charmDir2, "src"), 0700)
+	c.Assert(err, gc.IsNil)
+	err = os.Chtimes(filepath.Join(charmDir2, "metadata.yaml"), time.Now(), time.Now().Add(-time.Hour))
+	c.Assert(err, gc.IsNil)
+
+	archive := func(path string) []byte {
+		dir, err := charm.ReadCharmDir(path)
+		c.Assert(err, gc.IsNil)
+		var buf bytes.Buffer
+		err = dir.ArchiveToWithOptions(&buf, charm.ArchiveOptions{Reproducible: true})
+		c.Assert(err, gc.IsNil)
+		return buf.Bytes()
+	}
+	data := archive(charmDir1)
+	c.Assert(archive(charmDir2), jc.DeepEquals, data)
+
+	zipr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	c.Assert(err, gc.IsNil)
+	var names []string
+	for _, f := range zipr.File {
+		names = append(names, f.Name)
+		c.Check(f.Modified.Equal(charm.ReproducibleModTime), jc.IsTrue, gc.Commentf("%s", f.Name))
+		switch {
+		case f.Mode().IsDir():
+			c.Check(f.Mode(), gc.Equals, os.ModeDir|0755, gc.Commentf("%s", f.Name))
+		case f.Mode()&os.ModeSymlink != 0:
+			c.Check(f.Mode(), gc.Equals, os.ModeSymlink|0777, gc.Commentf("%s", f.Name))
+		case strings.HasPrefix(f.Name, "hooks/"):
+			c.Check(f.Mode(), gc.Equals, os.FileMode(0755), gc.Commentf("%s", f.Name))
+		default:
+			c.Check(f.Mode(), gc.Equals, os.FileMode(0644), gc.Commentf("%s", f.Name))
+		}
+	}
+	c.Assert(names, jc.DeepEquals, []string{
+		"./", "actions.yaml", "config.yaml", "empty/", "empty/.gitkeep", "hooks/",
+		"hooks/install", "metadata.yaml", "revision", "src/", "src/hello.c",
+	})
+}
+
 func (s *CharmDirSuite) TestDirRevisionFile(c *gc.C) {
 	charmDir := cloneDir(c, charmDirPath(c, "dummy"))
 	revPath := filepath.Join(charmDir, "revision")
@@ -0,0 +1,159 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package charm
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// IgnoreFileName holds the name of the file, at the root of a charm
+// directory, that lists the paths to exclude from archives of the charm.
+//
+// The file uses the same syntax as a .gitignore file. Each line holds a
+// pattern; blank lines and lines starting with "#" are ignored. A
+// pattern starting with "!" re-includes paths excluded by an earlier
+// pattern, and a pattern ending with "/" only matches directories. A
+// pattern containing a "/" other than at its end is matched against
+// the path relative to the charm root; any other pattern is matched
+// against the base name of paths at any depth. The wildcards "*", "?"
+// and "[...]" do not match "/", while "**" matches any number of
+// directories. The last pattern that matches a path decides whether
+// it is excluded. Files inside an excluded directory cannot be
+// re-included.
+const IgnoreFileName = ".charmignore"
+
+// ignoreRule holds a single pattern from an ignore file.
+type ignoreRule struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// ignoreMatcher decides which paths are excluded by an ignore file.
+type ignoreMatcher struct {
+	rules []ignoreRule
+}
+
+// readIgnoreFile reads the ignore file in the given charm directory.
+// It returns a nil matcher if there is no ignore file.
+func readIgnoreFile(dir string) (*ignoreMatcher, error) {
+	f, err := os.Open(filepath.Join(dir, IgnoreFileName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseIgnore(f)
+}
+
+// parseIgnore parses ignore patterns from r.
+func parseIgnore(r io.Reader) (*ignoreMatcher, error) {
+	m := &ignoreMatcher{}
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var rule ignoreRule
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			return nil, fmt.Errorf("%s line %d: empty pattern", IgnoreFileName, lineNum)
+		}
+		re, err := ignorePatternRegexp(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: invalid pattern %q: %v", IgnoreFileName, lineNum, line, err)
+		}
+		rule.re = re
+		m.rules = append(m.rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ignorePatternRegexp returns a regular expression that matches
+// the slash-separated paths matched by the given pattern.
+func ignorePatternRegexp(pattern string) (*regexp.Regexp, error) {
+	var buf bytes.Buffer
+	buf.WriteString("^")
+	if strings.HasPrefix(pattern, "/") {
+		pattern = pattern[1:]
+	} else if !strings.Contains(pattern, "/") {
+		// The pattern matches at any depth.
+		buf.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			buf.WriteString("(?:.*/)?")
+			i += 2
+		case pattern[i:] == "**":
+			buf.WriteString(".*")
+			i++
+		case c == '*':
+			buf.WriteString("[^/]*")
+		case c == '?':
+			buf.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated character class")
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				// Like the other wildcards, a negated
+				// class never matches a "/".
+				class = "^/" + class[1:]
+			}
+			buf.WriteString("[" + strings.Replace(class, `\`, `\\`, -1) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			buf.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		default:
+			buf.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	buf.WriteString("$")
+	return regexp.Compile(buf.String())
+}
+
+// match reports whether the given slash-separated path, relative to
+// the charm root, is excluded. The isDir parameter reports whether
+// the path refers to a directory.
+func (m *ignoreMatcher) match(path string, isDir bool) bool {
+	if m == nil {
+		return false
+	}
+	ignored := false
+	for _, rule := range m.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		if rule.re.MatchString(path) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
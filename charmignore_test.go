@@ -0,0 +1,196 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package charm_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"gopkg.in/juju/charm.v6"
+)
+
+type CharmIgnoreSuite struct{}
+
+var _ = gc.Suite(&CharmIgnoreSuite{})
+
+var ignoreMatchTests = []struct {
+	about    string
+	patterns string
+	path     string
+	isDir    bool
+	expect   bool
+}{{
+	about:    "base name at any depth",
+	patterns: "*.pyc",
+	path:     "lib/foo/bar.pyc",
+	expect:   true,
+}, {
+	about:    "wildcard does not match slash",
+	patterns: "lib/*.pyc",
+	path:     "lib/foo/bar.pyc",
+}, {
+	about:    "anchored pattern",
+	patterns: "/venv",
+	path:     "lib/venv",
+	isDir:    true,
+}, {
+	about:    "anchored pattern at root",
+	patterns: "/venv",
+	path:     "venv",
+	isDir:    true,
+	expect:   true,
+}, {
+	about:    "directory only pattern ignores files",
+	patterns: "tests/",
+	path:     "tests",
+}, {
+	about:    "directory only pattern matches directories",
+	patterns: "tests/",
+	path:     "lib/tests",
+	isDir:    true,
+	expect:   true,
+}, {
+	about:    "leading double star",
+	patterns: "**/fixtures",
+	path:     "a/b/fixtures",
+	isDir:    true,
+	expect:   true,
+}, {
+	about:    "middle double star matches no directories",
+	patterns: "a/**/b",
+	path:     "a/b",
+	expect:   true,
+}, {
+	about:    "middle double star matches several directories",
+	patterns: "a/**/b",
+	path:     "a/x/y/b",
+	expect:   true,
+}, {
+	about:    "trailing double star",
+	patterns: "docs/**",
+	path:     "docs/x/y.md",
+	expect:   true,
+}, {
+	about:    "trailing double star does not match the directory",
+	patterns: "docs/**",
+	path:     "docs",
+	isDir:    true,
+}, {
+	about:    "negation",
+	patterns: "*.txt\n!keep.txt\n",
+	path:     "keep.txt",
+}, {
+	about:    "last match wins",
+	patterns: "!keep.txt\n*.txt\n",
+	path:     "keep.txt",
+	expect:   true,
+}, {
+	about:    "comments and blank lines",
+	patterns: "# *.txt\n\n   \nfoo\n",
+	path:     "x.txt",
+}, {
+	about:    "escaped hash",
+	patterns: `\#notes`,
+	path:     "#notes",
+	expect:   true,
+}, {
+	about:    "character class",
+	patterns: "*.py[co]",
+	path:     "x.pyo",
+	expect:   true,
+}, {
+	about:    "negated character class",
+	patterns: "file[!0-9]",
+	path:     "file1",
+}, {
+	about:    "question mark",
+	patterns: "?.swp",
+	path:     "a.swp",
+	expect:   true,
+}}
+
+func (s *CharmIgnoreSuite) TestMatch(c *gc.C) {
+	for i, test := range ignoreMatchTests {
+		c.Logf("test %d: %s", i, test.about)
+		ignored, err := charm.IgnoreMatch(test.patterns, test.path, test.isDir)
+		c.Assert(err, jc.ErrorIsNil)
+		c.Assert(ignored, gc.Equals, test.expect)
+	}
+}
+
+func (s *CharmIgnoreSuite) TestInvalidPattern(c *gc.C) {
+	_, err := charm.IgnoreMatch("foo\n[abc\n", "foo", false)
+	c.Assert(err, gc.ErrorMatches, `.charmignore line 2: invalid pattern "\[abc": unterminated character class`)
+
+	_, err = charm.IgnoreMatch("foo\n!/\n", "foo", false)
+	c.Assert(err, gc.ErrorMatches, `.charmignore line 2: empty pattern`)
+}
+
+func (s *CharmIgnoreSuite) TestArchive(c *gc.C) {
+	charmDir := cloneDir(c, charmDirPath(c, "dummy"))
+	write := func(path, content string) {
+		path = filepath.Join(charmDir, filepath.FromSlash(path))
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		c.Assert(err, jc.ErrorIsNil)
+		err = ioutil.WriteFile(path, []byte(content), 0644)
+		c.Assert(err, jc.ErrorIsNil)
+	}
+	write(".charmignore", `
+# Development files.
+venv/
+*.pyc
+!src/keep.pyc
+/empty
+`)
+	write("venv/bin/python", "")
+	write("src/x.pyc", "")
+	write("src/keep.pyc", "")
+	write("lib/venv/x.py", "")
+	// Ignored special files are not an error.
+	err := syscall.Mkfifo(filepath.Join(charmDir, "src", "fifo.pyc"), 0644)
+	c.Assert(err, jc.ErrorIsNil)
+
+	dir, err := charm.ReadCharmDir(charmDir)
+	c.Assert(err, jc.ErrorIsNil)
+	names, err := dir.PreviewArchive()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(names, jc.DeepEquals, []string{
+		"actions.yaml",
+		"config.yaml",
+		"hooks/",
+		"hooks/install",
+		"lib/",
+		"metadata.yaml",
+		"revision",
+		"src/",
+		"src/hello.c",
+		"src/keep.pyc",
+	})
+
+	var buf bytes.Buffer
+	err = dir.ArchiveTo(&buf)
+	c.Assert(err, jc.ErrorIsNil)
+	archive, err := charm.ReadCharmArchiveBytes(buf.Bytes())
+	c.Assert(err, jc.ErrorIsNil)
+	manifest, err := archive.Manifest()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(manifest.SortedValues(), jc.DeepEquals, []string{
+		"actions.yaml",
+		"config.yaml",
+		"hooks",
+		"hooks/install",
+		"lib",
+		"metadata.yaml",
+		"revision",
+		"src",
+		"src/hello.c",
+		"src/keep.pyc",
+	})
+}
@@ -0,0 +1,104 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+// The charm-diff command reports the differences in the contract
+// between two revisions of a charm.
+//
+// Usage:
+//
+//	charm-diff [-format text|json] old new
+//
+// Each charm may be a charm directory or a charm archive. The command
+// exits with status 1 if any change is breaking, and with status 2 if
+// the charms cannot be compared at all.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/juju/charm.v6"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("charm-diff", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	format := flags.String("format", "text", "output format: text or json")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(stderr, "charm-diff: unknown format %q\n", *format)
+		return 2
+	}
+	if flags.NArg() != 2 {
+		fmt.Fprintln(stderr, "charm-diff: expected old and new charm paths")
+		return 2
+	}
+	var charms [2]charm.Charm
+	for i, path := range flags.Args() {
+		ch, err := charm.ReadCharm(path)
+		if err != nil {
+			fmt.Fprintf(stderr, "charm-diff: cannot read charm %q: %v\n", path, err)
+			return 2
+		}
+		charms[i] = ch
+	}
+	diff := charm.DiffCharms(charms[0], charms[1])
+	var err error
+	if *format == "json" {
+		err = writeJSON(stdout, diff)
+	} else {
+		err = writeText(stdout, diff)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "charm-diff: %v\n", err)
+		return 2
+	}
+	if diff.Breaking() {
+		return 1
+	}
+	return 0
+}
+
+func writeText(w io.Writer, diff *charm.CharmDiff) error {
+	for _, change := range diff.Changes {
+		mark := "compatible"
+		if change.Breaking {
+			mark = "breaking"
+		}
+		if _, err := fmt.Fprintf(w, "%-10s %-8s %s: %s\n", mark, change.Kind, change.Path, change.Description); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type jsonChange struct {
+	Kind        string `json:"kind"`
+	Path        string `json:"path"`
+	Breaking    bool   `json:"breaking"`
+	Description string `json:"description"`
+}
+
+func writeJSON(w io.Writer, diff *charm.CharmDiff) error {
+	changes := make([]jsonChange, 0, len(diff.Changes))
+	for _, change := range diff.Changes {
+		changes = append(changes, jsonChange{
+			Kind:        string(change.Kind),
+			Path:        change.Path,
+			Breaking:    change.Breaking,
+			Description: change.Description,
+		})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(changes)
+}
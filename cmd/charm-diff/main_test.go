@@ -0,0 +1,73 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	gc "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) {
+	gc.TestingT(t)
+}
+
+type mainSuite struct{}
+
+var _ = gc.Suite(&mainSuite{})
+
+func writeCharm(c *gc.C, metadata, config string) string {
+	dir := c.MkDir()
+	err := ioutil.WriteFile(filepath.Join(dir, "metadata.yaml"), []byte(metadata), 0644)
+	c.Assert(err, gc.IsNil)
+	err = ioutil.WriteFile(filepath.Join(dir, "config.yaml"), []byte(config), 0644)
+	c.Assert(err, gc.IsNil)
+	return dir
+}
+
+const metadata = `
+name: tiny
+summary: tiny
+description: tiny
+`
+
+func (s *mainSuite) TestUsage(c *gc.C) {
+	var stdout, stderr bytes.Buffer
+	status := run([]string{"onlyone"}, &stdout, &stderr)
+	c.Assert(status, gc.Equals, 2)
+	c.Assert(stderr.String(), gc.Equals, "charm-diff: expected old and new charm paths\n")
+}
+
+func (s *mainSuite) TestUnknownFormat(c *gc.C) {
+	var stdout, stderr bytes.Buffer
+	status := run([]string{"-format", "xml", "a", "b"}, &stdout, &stderr)
+	c.Assert(status, gc.Equals, 2)
+	c.Assert(stderr.String(), gc.Equals, "charm-diff: unknown format \"xml\"\n")
+}
+
+func (s *mainSuite) TestDiff(c *gc.C) {
+	old := writeCharm(c, metadata, "options:\n  port: {type: int}\n")
+	new := writeCharm(c, metadata, "options:\n  port: {type: int}\n  name: {type: string}\n")
+
+	var stdout, stderr bytes.Buffer
+	status := run([]string{old, new}, &stdout, &stderr)
+	c.Assert(status, gc.Equals, 0)
+	c.Assert(stdout.String(), gc.Equals, "compatible added    config.name: config option \"name\" added\n")
+
+	stdout.Reset()
+	status = run([]string{"-format", "json", new, old}, &stdout, &stderr)
+	c.Assert(status, gc.Equals, 1)
+	c.Assert(stdout.String(), gc.Equals, `[
+  {
+    "kind": "removed",
+    "path": "config.name",
+    "breaking": true,
+    "description": "config option \"name\" removed"
+  }
+]
+`)
+}
@@ -0,0 +1,90 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+// The charm-lint command checks charm directories for common mistakes.
+//
+// Usage:
+//
+//	charm-lint [-format text|json|junit] [-rules rule1,rule2] charmdir...
+//	charm-lint -list
+//
+// Only one charm directory may be given with the json and junit
+// formats, as each writes a single document. The command exits with
+// status 1 if any error is found, and with status 2 if a charm cannot
+// be linted at all.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gopkg.in/juju/charm.v6/lint"
+)
+
+var formats = map[string]func(io.Writer, *lint.Report) error{
+	"text":  lint.WriteText,
+	"json":  lint.WriteJSON,
+	"junit": lint.WriteJUnit,
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("charm-lint", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	format := flags.String("format", "text", "output format: text, json or junit")
+	ruleNames := flags.String("rules", "", "comma-separated list of rules to run (default all)")
+	list := flags.Bool("list", false, "list the available rules and exit")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *list {
+		for _, rule := range lint.Rules() {
+			fmt.Fprintf(stdout, "%-20s %-8s %s\n", rule.Name, rule.Severity, rule.Description)
+		}
+		return 0
+	}
+	write, ok := formats[*format]
+	if !ok {
+		fmt.Fprintf(stderr, "charm-lint: unknown format %q\n", *format)
+		return 2
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprintln(stderr, "charm-lint: no charm directory specified")
+		return 2
+	}
+	if flags.NArg() > 1 && *format != "text" {
+		fmt.Fprintf(stderr, "charm-lint: only one charm directory may be specified with format %q\n", *format)
+		return 2
+	}
+	var rules []lint.Rule
+	if *ruleNames != "" {
+		var err error
+		rules, err = lint.LookupRules(strings.Split(*ruleNames, ",")...)
+		if err != nil {
+			fmt.Fprintf(stderr, "charm-lint: %v\n", err)
+			return 2
+		}
+	}
+	status := 0
+	for _, path := range flags.Args() {
+		report, err := lint.Lint(path, rules...)
+		if err != nil {
+			fmt.Fprintf(stderr, "charm-lint: %v\n", err)
+			return 2
+		}
+		if err := write(stdout, report); err != nil {
+			fmt.Fprintf(stderr, "charm-lint: %v\n", err)
+			return 2
+		}
+		if report.HasErrors() {
+			status = 1
+		}
+	}
+	return status
+}
@@ -0,0 +1,76 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gc "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) {
+	gc.TestingT(t)
+}
+
+type mainSuite struct{}
+
+var _ = gc.Suite(&mainSuite{})
+
+func (s *mainSuite) TestList(c *gc.C) {
+	var stdout, stderr bytes.Buffer
+	status := run([]string{"-list"}, &stdout, &stderr)
+	c.Assert(status, gc.Equals, 0)
+	c.Assert(stdout.String(), gc.Matches, `(?s).*readme +warning +charms without a README file\n.*`)
+}
+
+func (s *mainSuite) TestUnknownFormat(c *gc.C) {
+	var stdout, stderr bytes.Buffer
+	status := run([]string{"-format", "xml", "somewhere"}, &stdout, &stderr)
+	c.Assert(status, gc.Equals, 2)
+	c.Assert(stderr.String(), gc.Equals, "charm-lint: unknown format \"xml\"\n")
+}
+
+func (s *mainSuite) TestUnknownRule(c *gc.C) {
+	var stdout, stderr bytes.Buffer
+	status := run([]string{"-rules", "readme,bogus", "somewhere"}, &stdout, &stderr)
+	c.Assert(status, gc.Equals, 2)
+	c.Assert(stderr.String(), gc.Equals, "charm-lint: lint rule \"bogus\" not found\n")
+}
+
+func (s *mainSuite) TestMultiplePathsMachineReadable(c *gc.C) {
+	for _, format := range []string{"json", "junit"} {
+		var stdout, stderr bytes.Buffer
+		status := run([]string{"-format", format, "one", "two"}, &stdout, &stderr)
+		c.Assert(status, gc.Equals, 2)
+		c.Assert(stdout.String(), gc.Equals, "")
+		c.Assert(stderr.String(), gc.Equals, "charm-lint: only one charm directory may be specified with format \""+format+"\"\n")
+	}
+}
+
+func (s *mainSuite) TestLint(c *gc.C) {
+	dir := c.MkDir()
+	err := ioutil.WriteFile(filepath.Join(dir, "metadata.yaml"), []byte(`
+name: tiny
+summary: tiny
+description: tiny
+`), 0644)
+	c.Assert(err, gc.IsNil)
+	err = os.Mkdir(filepath.Join(dir, "hooks"), 0755)
+	c.Assert(err, gc.IsNil)
+	err = ioutil.WriteFile(filepath.Join(dir, "hooks", "install"), nil, 0644)
+	c.Assert(err, gc.IsNil)
+
+	var stdout, stderr bytes.Buffer
+	status := run([]string{"-rules", "readme", dir}, &stdout, &stderr)
+	c.Assert(status, gc.Equals, 0)
+	c.Assert(stdout.String(), gc.Equals, dir+": warning: charm has no README file (readme)\n1 problem(s) found, 0 error(s)\n")
+
+	stdout.Reset()
+	status = run([]string{"-rules", "hook-not-executable", dir}, &stdout, &stderr)
+	c.Assert(status, gc.Equals, 1)
+}
@@ -7,7 +7,10 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/juju/schema"
 	"gopkg.in/yaml.v2"
@@ -23,16 +26,132 @@ type Option struct {
 	Type        string      `yaml:"type"`
 	Description string      `yaml:"description,omitempty"`
 	Default     interface{} `yaml:"default,omitempty"`
+
+	// Items holds the type of the elements of a list option, or of
+	// the values of a map option. It must be one of "string", "int",
+	// "float" or "boolean", and defaults to "string".
+	Items string `yaml:"items,omitempty"`
+
+	// Allowed holds the values that an enum option may take.
+	Allowed []string `yaml:"allowed,omitempty"`
+
+	// Minimum and Maximum hold the smallest and largest
+	// values allowed for int, float and bytes options.
+	Minimum *float64 `yaml:"minimum,omitempty"`
+	Maximum *float64 `yaml:"maximum,omitempty"`
+
+	// Pattern holds a regular expression that the whole
+	// value of a string or secret option must match.
+	Pattern string `yaml:"pattern,omitempty"`
+
+	// MinLength and MaxLength hold the smallest and largest number
+	// of characters allowed in the value of a string or secret
+	// option, or of elements in the value of a list or map option.
+	MinLength *int `yaml:"min-length,omitempty"`
+	MaxLength *int `yaml:"max-length,omitempty"`
+
+	// Requires holds the names of the options that must
+	// also be set when the option is set.
+	Requires []string `yaml:"requires,omitempty"`
+
+	// ConflictsWith holds the names of the options that
+	// must not be set when the option is set.
+	ConflictsWith []string `yaml:"conflicts-with,omitempty"`
+
+	// Deprecated records that the option should no longer be used.
+	Deprecated bool `yaml:"deprecated,omitempty"`
+
+	// RenamedTo holds the name of the option that replaces this
+	// one. Settings for this option are migrated to that option
+	// by Config.MigrateSettings.
+	RenamedTo string `yaml:"renamed-to,omitempty"`
+}
+
+// The supported option types are:
+//
+//	string    a string
+//	int       an int64
+//	float     a float64
+//	boolean   a bool
+//	secret    a string that is redacted by Config.RedactSettings
+//	list      a []interface{} holding values of the Items type
+//	map       a map[string]interface{} holding values of the Items type
+//	enum      a string that must be one of the Allowed values
+//	duration  a time.Duration, written as accepted by time.ParseDuration
+//	bytes     an int64 number of bytes, written as an integer with an
+//	          optional unit suffix of K, M, G, T or P (also accepted
+//	          as KB or KiB, and so on), each 1024 times the previous
+var optionTypeCheckers = map[string]schema.Checker{
+	"string":   schema.String(),
+	"int":      schema.Int(),
+	"float":    schema.Float(),
+	"boolean":  schema.Bool(),
+	"secret":   schema.String(),
+	"duration": durationC{},
+	"bytes":    bytesC{},
+}
+
+// scalarOptionTypes holds the types that may be
+// used for the items of list and map options.
+var scalarOptionTypes = map[string]bool{
+	"string":  true,
+	"int":     true,
+	"float":   true,
+	"boolean": true,
+}
+
+// itemType returns the type of the items of a list or map option.
+func (option Option) itemType() string {
+	if option.Items == "" {
+		return "string"
+	}
+	return option.Items
+}
+
+// typeDescription describes the values accepted by the option.
+func (option Option) typeDescription() string {
+	switch option.Type {
+	case "list":
+		return "list of " + option.itemType()
+	case "map":
+		return "map of " + option.itemType()
+	case "enum":
+		quoted := make([]string, len(option.Allowed))
+		for i, value := range option.Allowed {
+			quoted[i] = strconv.Quote(value)
+		}
+		return "one of " + strings.Join(quoted, ", ")
+	}
+	return option.Type
 }
 
 // error replaces any supplied non-nil error with a new error describing a
 // validation failure for the supplied value.
 func (option Option) error(err *error, name string, value interface{}) {
 	if *err != nil {
-		*err = fmt.Errorf("option %q expected %s, got %#v", name, option.Type, value)
+		*err = fmt.Errorf("option %q expected %s, got %#v", name, option.typeDescription(), value)
 	}
 }
 
+// checker returns the checker for values of the option,
+// or nil if the option type is not known.
+func (option Option) checker() schema.Checker {
+	switch option.Type {
+	case "list", "map":
+		if !scalarOptionTypes[option.itemType()] {
+			return nil
+		}
+		item := optionTypeCheckers[option.itemType()]
+		if option.Type == "list" {
+			return schema.List(item)
+		}
+		return schema.StringMap(item)
+	case "enum":
+		return enumC(option.Allowed)
+	}
+	return optionTypeCheckers[option.Type]
+}
+
 // validate returns an appropriately-typed value for the supplied value, or
 // returns an error if it cannot be converted to the correct type. Nil values
 // are always considered valid.
@@ -40,7 +159,7 @@ func (option Option) validate(name string, value interface{}) (_ interface{}, er
 	if value == nil {
 		return nil, nil
 	}
-	if checker := optionTypeCheckers[option.Type]; checker != nil {
+	if checker := option.checker(); checker != nil {
 		defer option.error(&err, name, value)
 		if value, err = checker.Coerce(value, nil); err != nil {
 			return nil, err
@@ -50,16 +169,9 @@ func (option Option) validate(name string, value interface{}) (_ interface{}, er
 	return nil, fmt.Errorf("option %q has unknown type %q", name, option.Type)
 }
 
-var optionTypeCheckers = map[string]schema.Checker{
-	"string":  schema.String(),
-	"int":     schema.Int(),
-	"float":   schema.Float(),
-	"boolean": schema.Bool(),
-}
-
 func (option Option) parse(name, str string) (val interface{}, err error) {
 	switch option.Type {
-	case "string":
+	case "string", "secret":
 		return str, nil
 	case "int":
 		val, err = strconv.ParseInt(str, 10, 64)
@@ -67,6 +179,16 @@ func (option Option) parse(name, str string) (val interface{}, err error) {
 		val, err = strconv.ParseFloat(str, 64)
 	case "boolean":
 		val, err = strconv.ParseBool(str)
+	case "duration":
+		val, err = time.ParseDuration(str)
+	case "bytes":
+		val, err = parseBytes(str)
+	case "enum":
+		val, err = enumC(option.Allowed).Coerce(str, nil)
+	case "list":
+		val, err = option.parseList(name, str)
+	case "map":
+		val, err = option.parseMap(name, str)
 	default:
 		return nil, fmt.Errorf("option %q has unknown type %q", name, option.Type)
 	}
@@ -75,6 +197,151 @@ func (option Option) parse(name, str string) (val interface{}, err error) {
 	return
 }
 
+// parseList parses a comma-separated list of items.
+func (option Option) parseList(name, str string) (interface{}, error) {
+	items := []interface{}{}
+	if strings.TrimSpace(str) == "" {
+		return items, nil
+	}
+	itemOption := Option{Type: option.itemType()}
+	for _, field := range strings.Split(str, ",") {
+		item, err := itemOption.parse(name, strings.TrimSpace(field))
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// parseMap parses a comma-separated list of key=value pairs.
+func (option Option) parseMap(name, str string) (interface{}, error) {
+	m := make(map[string]interface{})
+	if strings.TrimSpace(str) == "" {
+		return m, nil
+	}
+	itemOption := Option{Type: option.itemType()}
+	for _, field := range strings.Split(str, ",") {
+		parts := strings.SplitN(field, "=", 2)
+		key := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || key == "" {
+			return nil, fmt.Errorf("expected key=value, got %q", field)
+		}
+		value, err := itemOption.parse(name, strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, err
+		}
+		m[key] = value
+	}
+	return m, nil
+}
+
+// MarshalYAML implements yaml.Marshaler. Duration defaults are
+// written in the form in which they are read.
+func (option Option) MarshalYAML() (interface{}, error) {
+	type plainOption Option
+	out := plainOption(option)
+	if d, ok := out.Default.(time.Duration); ok {
+		out.Default = d.String()
+	}
+	return out, nil
+}
+
+// enumC is a checker that accepts the strings it holds.
+type enumC []string
+
+func (c enumC) Coerce(v interface{}, path []string) (interface{}, error) {
+	if s, ok := v.(string); ok {
+		for _, allowed := range c {
+			if s == allowed {
+				return s, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("unexpected value %#v", v)
+}
+
+// durationC is a checker that accepts time.Duration values
+// and strings in the format accepted by time.ParseDuration.
+type durationC struct{}
+
+func (durationC) Coerce(v interface{}, path []string) (interface{}, error) {
+	switch v := v.(type) {
+	case time.Duration:
+		return v, nil
+	case string:
+		return time.ParseDuration(v)
+	}
+	return nil, fmt.Errorf("unexpected value %#v", v)
+}
+
+// bytesC is a checker that accepts non-negative integers and
+// strings in the format accepted by parseBytes.
+type bytesC struct{}
+
+func (bytesC) Coerce(v interface{}, path []string) (interface{}, error) {
+	if s, ok := v.(string); ok {
+		return parseBytes(s)
+	}
+	n, err := schema.Int().Coerce(v, path)
+	if err != nil {
+		return nil, err
+	}
+	if n.(int64) < 0 {
+		return nil, fmt.Errorf("negative size %d", n)
+	}
+	return n, nil
+}
+
+// byteUnits maps unit suffixes to their multipliers.
+var byteUnits = map[string]int64{
+	"":  1,
+	"B": 1,
+	"K": 1 << 10,
+	"M": 1 << 20,
+	"G": 1 << 30,
+	"T": 1 << 40,
+	"P": 1 << 50,
+}
+
+// parseBytes parses a number of bytes with an optional unit
+// suffix, such as "512", "100K", "1.5G" or "2GiB".
+func parseBytes(str string) (int64, error) {
+	str = strings.TrimSpace(str)
+	i := strings.IndexFunc(str, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i == -1 {
+		i = len(str)
+	}
+	number, unit := str[:i], strings.TrimSpace(str[i:])
+	unit = strings.ToUpper(unit)
+	if strings.HasSuffix(unit, "IB") && len(unit) > 2 {
+		unit = strings.TrimSuffix(unit, "IB")
+	} else if len(unit) > 1 {
+		unit = strings.TrimSuffix(unit, "B")
+	}
+	multiplier, ok := byteUnits[unit]
+	if !ok || number == "" {
+		return 0, fmt.Errorf("invalid size %q", str)
+	}
+	if n, err := strconv.ParseInt(number, 10, 64); err == nil {
+		if n > math.MaxInt64/multiplier {
+			return 0, fmt.Errorf("size %q too large", str)
+		}
+		return n * multiplier, nil
+	}
+	f, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", str)
+	}
+	f *= float64(multiplier)
+	if f >= math.MaxInt64 {
+		return 0, fmt.Errorf("size %q too large", str)
+	}
+	return int64(f), nil
+}
+
 // Config represents the supported configuration options for a charm,
 // as declared in its config.yaml file.
 type Config struct {
@@ -115,19 +382,38 @@ func ReadConfig(r io.Reader) (*Config, error) {
 	}
 	for name, option := range config.Options {
 		switch option.Type {
-		case "string", "int", "float", "boolean":
+		case "string", "int", "float", "boolean", "secret", "duration", "bytes":
+		case "list", "map":
+			if option.Items != "" && !scalarOptionTypes[option.Items] {
+				return nil, fmt.Errorf("invalid config: option %q has unknown item type %q", name, option.Items)
+			}
+		case "enum":
+			if len(option.Allowed) == 0 {
+				return nil, fmt.Errorf("invalid config: option %q has no allowed values", name)
+			}
 		case "":
 			// Missing type is valid in python.
 			option.Type = "string"
 		default:
 			return nil, fmt.Errorf("invalid config: option %q has unknown type %q", name, option.Type)
 		}
+		if option.Items != "" && option.Type != "list" && option.Type != "map" {
+			return nil, fmt.Errorf("invalid config: option %q of type %q cannot specify items", name, option.Type)
+		}
+		if len(option.Allowed) > 0 && option.Type != "enum" {
+			return nil, fmt.Errorf("invalid config: option %q of type %q cannot specify allowed values", name, option.Type)
+		}
+		if err := config.checkConstraints(name, option); err != nil {
+			return nil, fmt.Errorf("invalid config: %v", err)
+		}
 		def := option.Default
-		if def == "" && option.Type == "string" {
+		if def == "" && (option.Type == "string" || option.Type == "secret") {
 			// Skip normal validation for compatibility with pyjuju.
 		} else if option.Default, err = option.validate(name, def); err != nil {
 			option.error(&err, name, def)
 			return nil, fmt.Errorf("invalid config default: %v", err)
+		} else if errs := option.checkValue(name, option.Default); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid config default: %v", errs[0])
 		}
 		config.Options[name] = option
 	}
@@ -144,42 +430,90 @@ func (c *Config) option(name string) (Option, error) {
 }
 
 // DefaultSettings returns settings containing the default value of every
-// option in the config. Default values may be nil.
+// option in the config, except those that have been renamed. Default
+// values may be nil.
 func (c *Config) DefaultSettings() Settings {
 	out := make(Settings)
 	for name, option := range c.Options {
-		out[name] = option.Default
+		if option.RenamedTo == "" {
+			out[name] = option.Default
+		}
 	}
 	return out
 }
 
 // ValidateSettings returns a copy of the supplied settings with a consistent type
-// for each value. It returns an error if the settings contain unknown keys
-// or invalid values.
+// for each value. It returns an error if the settings contain unknown keys,
+// invalid values, or values that violate the constraints of their options.
+// The error is a *SettingsError describing every problem found. Settings
+// for renamed options are first migrated as by MigrateSettings; callers
+// that want to report deprecation warnings should use
+// ValidateSettingsWithWarnings.
+//
+// As the requires and conflicts-with constraints relate options to each
+// other, the supplied settings should hold every setting of a charm that
+// is not left at its default.
 func (c *Config) ValidateSettings(settings Settings) (Settings, error) {
+	out, _, err := c.ValidateSettingsWithWarnings(settings)
+	return out, err
+}
+
+// ValidateSettingsWithWarnings is like ValidateSettings, but also returns
+// the deprecation warnings reported by MigrateSettings. The warnings are
+// returned even when the settings are not valid.
+func (c *Config) ValidateSettingsWithWarnings(settings Settings) (Settings, []DeprecationWarning, error) {
+	settings, warnings := c.MigrateSettings(settings)
+	checker := &settingsChecker{config: c}
+	out := checker.checkValues(settings)
+	checker.checkRelated(out)
+	if err := checker.err(); err != nil {
+		return nil, warnings, err
+	}
+	return out, warnings, nil
+}
+
+// RedactedValue replaces the values of secret
+// options in the settings returned by RedactSettings.
+const RedactedValue = "<redacted>"
+
+// RedactSettings returns a copy of the supplied settings in which the
+// value of every secret option is replaced by RedactedValue, so that
+// the settings may be safely displayed or logged. Nil values and
+// values for unknown options are left unchanged.
+func (c *Config) RedactSettings(settings Settings) Settings {
 	out := make(Settings)
 	for name, value := range settings {
-		if option, err := c.option(name); err != nil {
-			return nil, err
-		} else if value, err = option.validate(name, value); err != nil {
-			return nil, err
+		if option, ok := c.Options[name]; ok && option.Type == "secret" && value != nil {
+			value = RedactedValue
 		}
 		out[name] = value
 	}
-	return out, nil
+	return out
 }
 
 // FilterSettings returns the subset of the supplied settings that are valid.
+// Values that violate the constraints of their options are not included,
+// but the requires and conflicts-with constraints are not checked. Settings
+// for renamed options are migrated as by MigrateSettings; callers that
+// want to report deprecation warnings should use FilterSettingsWithWarnings.
 func (c *Config) FilterSettings(settings Settings) Settings {
+	out, _ := c.FilterSettingsWithWarnings(settings)
+	return out
+}
+
+// FilterSettingsWithWarnings is like FilterSettings, but also returns
+// the deprecation warnings reported by MigrateSettings.
+func (c *Config) FilterSettingsWithWarnings(settings Settings) (Settings, []DeprecationWarning) {
+	settings, warnings := c.MigrateSettings(settings)
 	out := make(Settings)
 	for name, value := range settings {
 		if option, err := c.option(name); err == nil {
-			if value, err := option.validate(name, value); err == nil {
+			if value, err := option.validate(name, value); err == nil && len(option.checkValue(name, value)) == 0 {
 				out[name] = value
 			}
 		}
 	}
-	return out
+	return out, warnings
 }
 
 // ParseSettingsStrings returns settings derived from the supplied map. Every
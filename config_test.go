@@ -7,6 +7,7 @@ import (
 	"bytes"
 	"fmt"
 	"strings"
+	"time"
 
 	jc "github.com/juju/testing/checkers"
 	gc "gopkg.in/check.v1"
@@ -480,3 +481,369 @@ func (s *ConfigSuite) TestErrorOnInvalidOptionTypes(c *gc.C) {
 	_, err = cfg.ParseSettingsYAML([]byte("testKey:\n  testOption: \"some string value\""), "testKey")
 	c.Assert(err, gc.ErrorMatches, "option \"testOption\" has unknown type \"invalid type\"")
 }
+
+func (s *ConfigSuite) TestRicherOptionTypes(c *gc.C) {
+	cfg, err := charm.ReadConfig(strings.NewReader(`
+options:
+    password:
+        type: secret
+    ports:
+        type: list
+        items: int
+        default: [80, 443]
+    names:
+        type: list
+    labels:
+        type: map
+        default: {a: x}
+    limits:
+        type: map
+        items: float
+    level:
+        type: enum
+        allowed: [debug, info, error]
+        default: info
+    timeout:
+        type: duration
+        default: 1m30s
+    cache:
+        type: bytes
+        default: 64M
+`))
+	c.Assert(err, gc.IsNil)
+	c.Assert(cfg.DefaultSettings(), jc.DeepEquals, charm.Settings{
+		"password": nil,
+		"ports":    []interface{}{int64(80), int64(443)},
+		"names":    nil,
+		"labels":   map[string]interface{}{"a": "x"},
+		"limits":   nil,
+		"level":    "info",
+		"timeout":  90 * time.Second,
+		"cache":    int64(64 << 20),
+	})
+
+	settings, err := cfg.ParseSettingsStrings(map[string]string{
+		"password": "hunter2",
+		"ports":    "22, 8080",
+		"names":    "",
+		"labels":   "a=1,b = 2",
+		"limits":   "cpu=1.5",
+		"level":    "debug",
+		"timeout":  "250ms",
+		"cache":    "1.5KiB",
+	})
+	c.Assert(err, gc.IsNil)
+	c.Assert(settings, jc.DeepEquals, charm.Settings{
+		"password": "hunter2",
+		"ports":    []interface{}{int64(22), int64(8080)},
+		"names":    []interface{}{},
+		"labels":   map[string]interface{}{"a": "1", "b": "2"},
+		"limits":   map[string]interface{}{"cpu": 1.5},
+		"level":    "debug",
+		"timeout":  250 * time.Millisecond,
+		"cache":    int64(1536),
+	})
+
+	settings, err = cfg.ParseSettingsYAML([]byte(`
+app:
+    ports: [1, 2]
+    labels: {k: v}
+    timeout: 2h
+    cache: 1024
+`), "app")
+	c.Assert(err, gc.IsNil)
+	c.Assert(settings, jc.DeepEquals, charm.Settings{
+		"ports":   []interface{}{int64(1), int64(2)},
+		"labels":  map[string]interface{}{"k": "v"},
+		"timeout": 2 * time.Hour,
+		"cache":   int64(1024),
+	})
+
+	newYAML, err := yaml.Marshal(cfg)
+	c.Assert(err, gc.IsNil)
+	newCfg, err := charm.ReadConfig(bytes.NewReader(newYAML))
+	c.Assert(err, gc.IsNil)
+	c.Assert(newCfg, jc.DeepEquals, cfg)
+}
+
+var richerOptionErrorTests = []struct {
+	about  string
+	option charm.Option
+	value  string
+	expect string
+}{{
+	about:  "list item of the wrong type",
+	option: charm.Option{Type: "list", Items: "int"},
+	value:  "1,two",
+	expect: `option "x" expected list of int, got "1,two"`,
+}, {
+	about:  "map entry without a value",
+	option: charm.Option{Type: "map"},
+	value:  "a=1,b",
+	expect: `option "x" expected map of string, got "a=1,b"`,
+}, {
+	about:  "value not allowed",
+	option: charm.Option{Type: "enum", Allowed: []string{"on", "off"}},
+	value:  "maybe",
+	expect: `option "x" expected one of "on", "off", got "maybe"`,
+}, {
+	about:  "invalid duration",
+	option: charm.Option{Type: "duration"},
+	value:  "10 parsecs",
+	expect: `option "x" expected duration, got "10 parsecs"`,
+}, {
+	about:  "unknown size unit",
+	option: charm.Option{Type: "bytes"},
+	value:  "10X",
+	expect: `option "x" expected bytes, got "10X"`,
+}, {
+	about:  "negative size",
+	option: charm.Option{Type: "bytes"},
+	value:  "-1K",
+	expect: `option "x" expected bytes, got "-1K"`,
+}}
+
+func (s *ConfigSuite) TestRicherOptionTypeErrors(c *gc.C) {
+	for i, test := range richerOptionErrorTests {
+		c.Logf("test %d: %s", i, test.about)
+		cfg := &charm.Config{Options: map[string]charm.Option{"x": test.option}}
+		_, err := cfg.ParseSettingsStrings(map[string]string{"x": test.value})
+		c.Check(err, gc.ErrorMatches, test.expect)
+	}
+}
+
+func (s *ConfigSuite) TestParseBytes(c *gc.C) {
+	cfg := &charm.Config{Options: map[string]charm.Option{"x": {Type: "bytes"}}}
+	for str, expect := range map[string]int64{
+		"0":     0,
+		"512":   512,
+		"512B":  512,
+		"2K":    2 << 10,
+		"2kb":   2 << 10,
+		"2KiB":  2 << 10,
+		"3M":    3 << 20,
+		"0.5G":  1 << 29,
+		"1T":    1 << 40,
+		"1PiB":  1 << 50,
+		" 4 M ": 4 << 20,
+	} {
+		c.Logf("size %q", str)
+		settings, err := cfg.ParseSettingsStrings(map[string]string{"x": str})
+		c.Assert(err, gc.IsNil)
+		c.Check(settings["x"], gc.Equals, expect)
+	}
+}
+
+var invalidRicherConfigTests = []struct {
+	about  string
+	config string
+	expect string
+}{{
+	about:  "unknown item type",
+	config: `options: {x: {type: list, items: list}}`,
+	expect: `invalid config: option "x" has unknown item type "list"`,
+}, {
+	about:  "enum without allowed values",
+	config: `options: {x: {type: enum}}`,
+	expect: `invalid config: option "x" has no allowed values`,
+}, {
+	about:  "items on a scalar option",
+	config: `options: {x: {type: string, items: int}}`,
+	expect: `invalid config: option "x" of type "string" cannot specify items`,
+}, {
+	about:  "allowed values on a non-enum option",
+	config: `options: {x: {type: int, allowed: [a]}}`,
+	expect: `invalid config: option "x" of type "int" cannot specify allowed values`,
+}, {
+	about:  "default not allowed",
+	config: `options: {x: {type: enum, allowed: [a, b], default: c}}`,
+	expect: `invalid config default: option "x" expected one of "a", "b", got "c"`,
+}}
+
+func (s *ConfigSuite) TestInvalidRicherConfig(c *gc.C) {
+	for i, test := range invalidRicherConfigTests {
+		c.Logf("test %d: %s", i, test.about)
+		_, err := charm.ReadConfig(strings.NewReader(test.config))
+		c.Check(err, gc.ErrorMatches, test.expect)
+	}
+}
+
+func (s *ConfigSuite) TestRedactSettings(c *gc.C) {
+	cfg := &charm.Config{Options: map[string]charm.Option{
+		"password": {Type: "secret"},
+		"token":    {Type: "secret"},
+		"username": {Type: "string"},
+	}}
+	settings := charm.Settings{
+		"password": "hunter2",
+		"token":    nil,
+		"username": "admin",
+		"unknown":  "value",
+	}
+	c.Assert(cfg.RedactSettings(settings), jc.DeepEquals, charm.Settings{
+		"password": charm.RedactedValue,
+		"token":    nil,
+		"username": "admin",
+		"unknown":  "value",
+	})
+	c.Assert(settings["password"], gc.Equals, "hunter2")
+}
+
+var constrainedConfig = `
+options:
+    port:
+        type: int
+        minimum: 1
+        maximum: 65535
+    ratio:
+        type: float
+        maximum: 1
+    name:
+        type: string
+        pattern: '[a-z][a-z0-9-]*'
+        min-length: 2
+        max-length: 8
+    password:
+        type: secret
+        pattern: '\S+'
+    hosts:
+        type: list
+        min-length: 1
+    tls-cert:
+        type: string
+        requires: [tls-key]
+        conflicts-with: [insecure]
+    tls-key:
+        type: string
+    insecure:
+        type: boolean
+    cache:
+        type: bytes
+        requires: [cache-dir]
+    cache-dir:
+        type: string
+        default: /var/cache
+`
+
+func (s *ConfigSuite) TestValidateSettingsConstraints(c *gc.C) {
+	cfg, err := charm.ReadConfig(strings.NewReader(constrainedConfig))
+	c.Assert(err, gc.IsNil)
+
+	settings := charm.Settings{
+		"port":     65535,
+		"ratio":    0.5,
+		"name":     "web-1",
+		"password": "hunter2",
+		"hosts":    []interface{}{"a"},
+		"tls-cert": "cert",
+		"tls-key":  "key",
+		"insecure": nil,
+		"cache":    "1G",
+	}
+	result, err := cfg.ValidateSettings(settings)
+	c.Assert(err, gc.IsNil)
+	c.Assert(result["port"], gc.Equals, int64(65535))
+
+	_, err = cfg.ValidateSettings(charm.Settings{
+		"port":     0,
+		"ratio":    1.5,
+		"name":     "Web_Server",
+		"password": "two words",
+		"hosts":    []interface{}{},
+		"tls-cert": "cert",
+		"insecure": true,
+		"unknown":  "value",
+	})
+	c.Assert(err, gc.FitsTypeOf, &charm.SettingsError{})
+	var options, messages []string
+	for _, err := range err.(*charm.SettingsError).Errors {
+		options = append(options, err.Option)
+		messages = append(messages, err.Error())
+	}
+	c.Assert(options, jc.DeepEquals, []string{
+		"hosts",
+		"name",
+		"name",
+		"password",
+		"port",
+		"ratio",
+		"tls-cert",
+		"tls-cert",
+		"unknown",
+	})
+	c.Assert(messages, jc.DeepEquals, []string{
+		`option "hosts" must have at least 1 item, got 0`,
+		`option "name" must have at most 8 characters, got 10`,
+		`option "name" must match pattern "[a-z][a-z0-9-]*", got "Web_Server"`,
+		`option "password" must match pattern "\\S+"`,
+		`option "port" must be at least 1, got 0`,
+		`option "ratio" must be at most 1, got 1.5`,
+		`option "tls-cert" requires option "tls-key" to be set`,
+		`option "tls-cert" conflicts with option "insecure"`,
+		`unknown option "unknown"`,
+	})
+	c.Assert(err, gc.ErrorMatches, `option "hosts" must have at least 1 item, got 0 \(and 8 more errors\)`)
+}
+
+func (s *ConfigSuite) TestFilterSettingsConstraints(c *gc.C) {
+	cfg, err := charm.ReadConfig(strings.NewReader(constrainedConfig))
+	c.Assert(err, gc.IsNil)
+	c.Assert(cfg.FilterSettings(charm.Settings{
+		"port":     80,
+		"ratio":    2.0,
+		"tls-cert": "cert",
+	}), jc.DeepEquals, charm.Settings{
+		"port":     int64(80),
+		"tls-cert": "cert",
+	})
+}
+
+var invalidConstraintTests = []struct {
+	about  string
+	config string
+	expect string
+}{{
+	about:  "minimum on a string option",
+	config: `options: {x: {type: string, minimum: 1}}`,
+	expect: `invalid config: option "x" of type "string" cannot specify minimum`,
+}, {
+	about:  "pattern on an int option",
+	config: `options: {x: {type: int, pattern: a}}`,
+	expect: `invalid config: option "x" of type "int" cannot specify pattern`,
+}, {
+	about:  "minimum greater than maximum",
+	config: `options: {x: {type: int, minimum: 2, maximum: 1}}`,
+	expect: `invalid config: option "x" has minimum greater than maximum`,
+}, {
+	about:  "invalid pattern",
+	config: `options: {x: {type: string, pattern: "("}}`,
+	expect: `invalid config: option "x" has invalid pattern: .*`,
+}, {
+	about:  "negative length",
+	config: `options: {x: {type: list, max-length: -1}}`,
+	expect: `invalid config: option "x" has negative length limit`,
+}, {
+	about:  "min-length greater than max-length",
+	config: `options: {x: {type: string, min-length: 3, max-length: 2}}`,
+	expect: `invalid config: option "x" has min-length greater than max-length`,
+}, {
+	about:  "requires unknown option",
+	config: `options: {x: {type: string, requires: [y]}}`,
+	expect: `invalid config: option "x" requires unknown option "y"`,
+}, {
+	about:  "conflicts with itself",
+	config: `options: {x: {type: string, conflicts-with: [x]}}`,
+	expect: `invalid config: option "x" conflicts with itself`,
+}, {
+	about:  "default violates constraint",
+	config: `options: {x: {type: int, maximum: 10, default: 11}}`,
+	expect: `invalid config default: option "x" must be at most 10, got 11`,
+}}
+
+func (s *ConfigSuite) TestInvalidConstraints(c *gc.C) {
+	for i, test := range invalidConstraintTests {
+		c.Logf("test %d: %s", i, test.about)
+		_, err := charm.ReadConfig(strings.NewReader(test.config))
+		c.Check(err, gc.ErrorMatches, test.expect)
+	}
+}
@@ -0,0 +1,241 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package charm
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"unicode/utf8"
+)
+
+// OptionError describes a setting that is not valid.
+type OptionError struct {
+	// Option holds the name of the option.
+	Option string
+
+	// Err holds the reason the setting is not valid.
+	Err error
+}
+
+func (err *OptionError) Error() string {
+	return err.Err.Error()
+}
+
+// SettingsError holds all the problems found by Config.ValidateSettings,
+// sorted by option name.
+type SettingsError struct {
+	Errors []*OptionError
+}
+
+func (err *SettingsError) Error() string {
+	switch len(err.Errors) {
+	case 0:
+		return "no settings errors!"
+	case 1:
+		return err.Errors[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", err.Errors[0], len(err.Errors)-1)
+}
+
+// settingsChecker accumulates the problems found in settings.
+type settingsChecker struct {
+	config *Config
+	errs   []*OptionError
+}
+
+func (checker *settingsChecker) addError(name string, err error) {
+	checker.errs = append(checker.errs, &OptionError{
+		Option: name,
+		Err:    err,
+	})
+}
+
+// checkValues returns a copy of the given settings with a consistent
+// type for each value. Unknown and invalid settings are not included.
+func (checker *settingsChecker) checkValues(settings Settings) Settings {
+	out := make(Settings)
+	for name, value := range settings {
+		option, err := checker.config.option(name)
+		if err != nil {
+			checker.addError(name, err)
+			continue
+		}
+		if value, err = option.validate(name, value); err != nil {
+			checker.addError(name, err)
+			continue
+		}
+		for _, err := range option.checkValue(name, value) {
+			checker.addError(name, err)
+		}
+		out[name] = value
+	}
+	return out
+}
+
+// checkRelated checks the requires and conflicts-with
+// constraints of every option in the given settings.
+func (checker *settingsChecker) checkRelated(settings Settings) {
+	for name := range settings {
+		for _, err := range checker.config.checkRelated(name, settings) {
+			checker.addError(name, err)
+		}
+	}
+}
+
+// err returns a *SettingsError holding the problems
+// found, sorted by option name, or nil if there are none.
+func (checker *settingsChecker) err() error {
+	if len(checker.errs) == 0 {
+		return nil
+	}
+	sort.SliceStable(checker.errs, func(i, j int) bool {
+		return checker.errs[i].Option < checker.errs[j].Option
+	})
+	return &SettingsError{checker.errs}
+}
+
+// checkConstraints checks that the constraints of the named option
+// are consistent with its type and with the other options.
+func (c *Config) checkConstraints(name string, option Option) error {
+	numeric := option.Type == "int" || option.Type == "float" || option.Type == "bytes"
+	stringlike := option.Type == "string" || option.Type == "secret"
+	sized := stringlike || option.Type == "list" || option.Type == "map"
+	for _, field := range []struct {
+		name    string
+		set     bool
+		allowed bool
+	}{
+		{"minimum", option.Minimum != nil, numeric},
+		{"maximum", option.Maximum != nil, numeric},
+		{"pattern", option.Pattern != "", stringlike},
+		{"min-length", option.MinLength != nil, sized},
+		{"max-length", option.MaxLength != nil, sized},
+	} {
+		if field.set && !field.allowed {
+			return fmt.Errorf("option %q of type %q cannot specify %s", name, option.Type, field.name)
+		}
+	}
+	if option.Minimum != nil && option.Maximum != nil && *option.Minimum > *option.Maximum {
+		return fmt.Errorf("option %q has minimum greater than maximum", name)
+	}
+	if option.Pattern != "" {
+		if _, err := option.patternRegexp(); err != nil {
+			return fmt.Errorf("option %q has invalid pattern: %v", name, err)
+		}
+	}
+	if option.MinLength != nil && *option.MinLength < 0 || option.MaxLength != nil && *option.MaxLength < 0 {
+		return fmt.Errorf("option %q has negative length limit", name)
+	}
+	if option.MinLength != nil && option.MaxLength != nil && *option.MinLength > *option.MaxLength {
+		return fmt.Errorf("option %q has min-length greater than max-length", name)
+	}
+	for _, other := range option.Requires {
+		if other == name {
+			return fmt.Errorf("option %q requires itself", name)
+		}
+		if _, ok := c.Options[other]; !ok {
+			return fmt.Errorf("option %q requires unknown option %q", name, other)
+		}
+	}
+	for _, other := range option.ConflictsWith {
+		if other == name {
+			return fmt.Errorf("option %q conflicts with itself", name)
+		}
+		if _, ok := c.Options[other]; !ok {
+			return fmt.Errorf("option %q conflicts with unknown option %q", name, other)
+		}
+	}
+	return c.checkRename(name, option)
+}
+
+// patternRegexp returns the compiled pattern of the option,
+// anchored so that it must match the whole value.
+func (option Option) patternRegexp() (*regexp.Regexp, error) {
+	return regexp.Compile("^(?:" + option.Pattern + ")$")
+}
+
+// checkValue returns the violations of the option's constraints by
+// the given value, which must have been returned by option.validate.
+func (option Option) checkValue(name string, value interface{}) []error {
+	var errs []error
+	addErrorf := func(f string, a ...interface{}) {
+		errs = append(errs, fmt.Errorf(f, a...))
+	}
+	var number float64
+	switch value := value.(type) {
+	case nil:
+		return nil
+	case int64:
+		number = float64(value)
+	case float64:
+		number = value
+	}
+	if option.Minimum != nil && number < *option.Minimum {
+		addErrorf("option %q must be at least %v, got %v", name, *option.Minimum, value)
+	}
+	if option.Maximum != nil && number > *option.Maximum {
+		addErrorf("option %q must be at most %v, got %v", name, *option.Maximum, value)
+	}
+
+	length, unit := 0, "item"
+	switch value := value.(type) {
+	case string:
+		length, unit = utf8.RuneCountInString(value), "character"
+	case []interface{}:
+		length = len(value)
+	case map[string]interface{}:
+		length = len(value)
+	}
+	units := func(n int) string {
+		if n == 1 {
+			return unit
+		}
+		return unit + "s"
+	}
+	if min := option.MinLength; min != nil && length < *min {
+		addErrorf("option %q must have at least %d %s, got %d", name, *min, units(*min), length)
+	}
+	if max := option.MaxLength; max != nil && length > *max {
+		addErrorf("option %q must have at most %d %s, got %d", name, *max, units(*max), length)
+	}
+
+	if s, ok := value.(string); ok && option.Pattern != "" {
+		re, err := option.patternRegexp()
+		if err != nil {
+			addErrorf("option %q has invalid pattern: %v", name, err)
+		} else if !re.MatchString(s) {
+			if option.Type == "secret" {
+				// Never reveal the value of a secret.
+				addErrorf("option %q must match pattern %q", name, option.Pattern)
+			} else {
+				addErrorf("option %q must match pattern %q, got %q", name, option.Pattern, s)
+			}
+		}
+	}
+	return errs
+}
+
+// checkRelated returns the violations of the requires and conflicts-with
+// constraints of the named option by the given settings. An option is
+// set when it has a non-nil value. A required option that is not set
+// is also satisfied by a non-nil default.
+func (c *Config) checkRelated(name string, settings Settings) []error {
+	if settings[name] == nil {
+		return nil
+	}
+	option := c.Options[name]
+	var errs []error
+	for _, other := range option.Requires {
+		if settings[other] == nil && c.Options[other].Default == nil {
+			errs = append(errs, fmt.Errorf("option %q requires option %q to be set", name, other))
+		}
+	}
+	for _, other := range option.ConflictsWith {
+		if settings[other] != nil {
+			errs = append(errs, fmt.Errorf("option %q conflicts with option %q", name, other))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,332 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package charm
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// The tag used by Config.Decode and ConfigFromStruct to map struct
+// fields to options is of the form:
+//
+//	charm:"name[,type]"
+//
+// where name is the name of the option and the optional type overrides
+// the option type that ConfigFromStruct derives from the field's Go
+// type, so that, for example, a string field may be declared as a
+// secret. Fields without the tag, or tagged "-", are ignored.
+const configTag = "charm"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// configField describes a struct field tagged with configTag.
+type configField struct {
+	index      []int
+	field      reflect.StructField
+	name       string
+	optionType string
+}
+
+// configFields returns the tagged fields of the given struct type.
+func configFields(t reflect.Type) ([]configField, error) {
+	var fields []configField
+	seen := make(map[string]string)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get(configTag)
+		if tag == "" || tag == "-" {
+			continue
+		}
+		if f.PkgPath != "" {
+			return nil, fmt.Errorf("field %s is not exported", f.Name)
+		}
+		parts := strings.Split(tag, ",")
+		if len(parts) > 2 || parts[0] == "" {
+			return nil, fmt.Errorf("field %s has invalid %s tag %q", f.Name, configTag, tag)
+		}
+		field := configField{
+			index: f.Index,
+			field: f,
+			name:  parts[0],
+		}
+		if len(parts) == 2 {
+			field.optionType = parts[1]
+		}
+		if other, ok := seen[field.name]; ok {
+			return nil, fmt.Errorf("fields %s and %s are both tagged with option %q", other, f.Name, field.name)
+		}
+		seen[field.name] = f.Name
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// Decode validates the given settings as by ValidateSettings and stores
+// them in the struct pointed to by out. Each field of the struct tagged
+// with the name of an option (see ConfigFromStruct) is set to the value
+// of the option, or to its default if the settings hold no value for
+// it. Fields of options that have neither are left unchanged.
+//
+// Every tagged field must name an option of a compatible type: string,
+// secret and enum options may be stored in string fields; int and bytes
+// options in integer fields; float options in float fields; boolean
+// options in bool fields; duration options in time.Duration fields; and
+// list and map options in slices and string-keyed maps of a type
+// compatible with their items.
+func (c *Config) Decode(settings Settings, out interface{}) error {
+	v := reflect.ValueOf(out)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("cannot decode settings into %T: not a pointer to a struct", out)
+	}
+	v = v.Elem()
+	fields, err := configFields(v.Type())
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		option, err := c.option(f.name)
+		if err != nil {
+			return fmt.Errorf("field %s: %v", f.field.Name, err)
+		}
+		if !option.compatibleWith(f.field.Type) {
+			return fmt.Errorf("field %s of type %s is not compatible with option %q of type %q", f.field.Name, f.field.Type, f.name, option.Type)
+		}
+	}
+	settings, err = c.ValidateSettings(settings)
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		value := settings[f.name]
+		if value == nil {
+			value = c.Options[f.name].Default
+		}
+		if value == nil {
+			continue
+		}
+		if err := setConfigValue(v.FieldByIndex(f.index), value); err != nil {
+			return fmt.Errorf("cannot set field %s from option %q: %v", f.field.Name, f.name, err)
+		}
+	}
+	return nil
+}
+
+// compatibleWith reports whether values of the option
+// can be stored in values of the given type.
+func (option Option) compatibleWith(t reflect.Type) bool {
+	if t == durationType {
+		return option.Type == "duration"
+	}
+	switch option.Type {
+	case "", "string", "secret", "enum":
+		return t.Kind() == reflect.String
+	case "int", "bytes":
+		return isIntKind(t.Kind())
+	case "float":
+		return t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64
+	case "boolean":
+		return t.Kind() == reflect.Bool
+	case "list":
+		return t.Kind() == reflect.Slice && Option{Type: option.itemType()}.compatibleWith(t.Elem())
+	case "map":
+		return t.Kind() == reflect.Map && t.Key().Kind() == reflect.String &&
+			Option{Type: option.itemType()}.compatibleWith(t.Elem())
+	}
+	return false
+}
+
+func isIntKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// setConfigValue sets v to the given value, which must have been
+// returned by Option.validate for an option compatible with v.
+func setConfigValue(v reflect.Value, value interface{}) error {
+	switch value := value.(type) {
+	case string:
+		v.SetString(value)
+	case bool:
+		v.SetBool(value)
+	case float64:
+		if v.OverflowFloat(value) {
+			return fmt.Errorf("value %v overflows %s", value, v.Type())
+		}
+		v.SetFloat(value)
+	case time.Duration:
+		v.SetInt(int64(value))
+	case int64:
+		switch v.Kind() {
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if value < 0 || v.OverflowUint(uint64(value)) {
+				return fmt.Errorf("value %d overflows %s", value, v.Type())
+			}
+			v.SetUint(uint64(value))
+		default:
+			if v.OverflowInt(value) {
+				return fmt.Errorf("value %d overflows %s", value, v.Type())
+			}
+			v.SetInt(value)
+		}
+	case []interface{}:
+		s := reflect.MakeSlice(v.Type(), len(value), len(value))
+		for i, item := range value {
+			if err := setConfigValue(s.Index(i), item); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+	case map[string]interface{}:
+		m := reflect.MakeMap(v.Type())
+		for key, item := range value {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := setConfigValue(elem, item); err != nil {
+				return err
+			}
+			m.SetMapIndex(reflect.ValueOf(key).Convert(v.Type().Key()), elem)
+		}
+		v.Set(m)
+	default:
+		return fmt.Errorf("unexpected value %#v", value)
+	}
+	return nil
+}
+
+// ConfigFromStruct returns a config with an option for every field of
+// the given struct, or pointer to a struct, that is tagged as described
+// by Config.Decode. The type of each option is derived from the type of
+// its field, unless given in the tag, and its description is taken from
+// any "description" tag of the field. Fields that do not hold their zero
+// value provide the defaults of their options.
+func ConfigFromStruct(v interface{}) (*Config, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cannot derive config from %T: not a struct", v)
+	}
+	fields, err := configFields(rv.Type())
+	if err != nil {
+		return nil, err
+	}
+	config := NewConfig()
+	for _, f := range fields {
+		option := Option{
+			Type:        f.optionType,
+			Description: f.field.Tag.Get("description"),
+		}
+		if option.Type == "" {
+			option.Type, option.Items = optionTypeOf(f.field.Type)
+		} else if option.Type == "list" || option.Type == "map" {
+			_, option.Items = optionTypeOf(f.field.Type)
+		}
+		if option.Type == "" || !option.compatibleWith(f.field.Type) {
+			return nil, fmt.Errorf("field %s of type %s cannot be used for an option of type %q", f.field.Name, f.field.Type, option.Type)
+		}
+		if option.Type == "enum" {
+			return nil, fmt.Errorf("field %s cannot be used for an enum option: the allowed values are unknown", f.field.Name)
+		}
+		if fv := rv.FieldByIndex(f.index); !isZeroValue(fv) {
+			value, err := configValueOf(fv)
+			if err == nil {
+				option.Default, err = option.validate(f.name, value)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("invalid default for field %s: %v", f.field.Name, err)
+			}
+		}
+		config.Options[f.name] = option
+	}
+	return config, nil
+}
+
+// optionTypeOf returns the option type, and any item type,
+// derived from the given Go type, or "" if there is none.
+func optionTypeOf(t reflect.Type) (optionType, items string) {
+	if t == durationType {
+		return "duration", ""
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string", ""
+	case reflect.Bool:
+		return "boolean", ""
+	case reflect.Float32, reflect.Float64:
+		return "float", ""
+	case reflect.Slice, reflect.Map:
+		if t.Kind() == reflect.Map && t.Key().Kind() != reflect.String {
+			return "", ""
+		}
+		items, _ := optionTypeOf(t.Elem())
+		if !scalarOptionTypes[items] {
+			return "", ""
+		}
+		if t.Kind() == reflect.Slice {
+			return "list", items
+		}
+		return "map", items
+	}
+	if isIntKind(t.Kind()) {
+		return "int", ""
+	}
+	return "", ""
+}
+
+// configValueOf returns the value held by v in a form
+// accepted by Option.validate. Unsigned integers are
+// converted to int64, as the option checkers accept
+// only signed integers.
+func configValueOf(v reflect.Value) (interface{}, error) {
+	if v.Type() == durationType {
+		return time.Duration(v.Int()), nil
+	}
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u := v.Uint()
+		if u > math.MaxInt64 {
+			return nil, fmt.Errorf("value %d overflows int64", u)
+		}
+		return int64(u), nil
+	case reflect.Slice:
+		items := make([]interface{}, v.Len())
+		for i := range items {
+			item, err := configValueOf(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	case reflect.Map:
+		m := make(map[string]interface{})
+		for _, key := range v.MapKeys() {
+			value, err := configValueOf(v.MapIndex(key))
+			if err != nil {
+				return nil, err
+			}
+			m[key.String()] = value
+		}
+		return m, nil
+	}
+	return v.Interface(), nil
+}
+
+// isZeroValue reports whether v holds the zero value of its type.
+// Empty slices and maps are considered zero.
+func isZeroValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
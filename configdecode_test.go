@@ -0,0 +1,238 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package charm_test
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"gopkg.in/juju/charm.v6"
+)
+
+type ConfigDecodeSuite struct{}
+
+var _ = gc.Suite(&ConfigDecodeSuite{})
+
+const decodeConfig = `
+options:
+    name:
+        type: string
+        default: app
+    password:
+        type: secret
+    port:
+        type: int
+        default: 80
+    ratio:
+        type: float
+    debug:
+        type: boolean
+    timeout:
+        type: duration
+        default: 30s
+    cache:
+        type: bytes
+    hosts:
+        type: list
+    ports:
+        type: list
+        items: int
+    labels:
+        type: map
+`
+
+type decodedConfig struct {
+	Name     string            `charm:"name"`
+	Password string            `charm:"password"`
+	Port     uint16            `charm:"port"`
+	Ratio    float64           `charm:"ratio"`
+	Debug    bool              `charm:"debug"`
+	Timeout  time.Duration     `charm:"timeout"`
+	Cache    int64             `charm:"cache"`
+	Hosts    []string          `charm:"hosts"`
+	Ports    []int             `charm:"ports"`
+	Labels   map[string]string `charm:"labels"`
+	Other    string
+	Ignored  string `charm:"-"`
+}
+
+func (s *ConfigDecodeSuite) TestDecode(c *gc.C) {
+	config, err := charm.ReadConfig(strings.NewReader(decodeConfig))
+	c.Assert(err, gc.IsNil)
+	out := decodedConfig{
+		Password: "unchanged",
+		Other:    "other",
+	}
+	err = config.Decode(charm.Settings{
+		"port":   8080,
+		"ratio":  0.5,
+		"debug":  true,
+		"cache":  "2K",
+		"hosts":  []interface{}{"a", "b"},
+		"ports":  []interface{}{1, 2},
+		"labels": map[string]interface{}{"k": "v"},
+	}, &out)
+	c.Assert(err, gc.IsNil)
+	c.Assert(out, jc.DeepEquals, decodedConfig{
+		Name:     "app",
+		Password: "unchanged",
+		Port:     8080,
+		Ratio:    0.5,
+		Debug:    true,
+		Timeout:  30 * time.Second,
+		Cache:    2048,
+		Hosts:    []string{"a", "b"},
+		Ports:    []int{1, 2},
+		Labels:   map[string]string{"k": "v"},
+		Other:    "other",
+	})
+}
+
+var decodeErrorTests = []struct {
+	about    string
+	out      interface{}
+	settings charm.Settings
+	expect   string
+}{{
+	about:  "not a pointer",
+	out:    decodedConfig{},
+	expect: `cannot decode settings into charm_test.decodedConfig: not a pointer to a struct`,
+}, {
+	about: "unknown option",
+	out: &struct {
+		X string `charm:"x"`
+	}{},
+	expect: `field X: unknown option "x"`,
+}, {
+	about: "incompatible field",
+	out: &struct {
+		Port string `charm:"port"`
+	}{},
+	expect: `field Port of type string is not compatible with option "port" of type "int"`,
+}, {
+	about: "duration into integer",
+	out: &struct {
+		Port time.Duration `charm:"port"`
+	}{},
+	expect: `field Port of type time.Duration is not compatible with option "port" of type "int"`,
+}, {
+	about: "incompatible items",
+	out: &struct {
+		Ports []string `charm:"ports"`
+	}{},
+	expect: `field Ports of type \[\]string is not compatible with option "ports" of type "list"`,
+}, {
+	about: "invalid settings",
+	out: &struct {
+		Port int `charm:"port"`
+	}{},
+	settings: charm.Settings{"port": "x"},
+	expect:   `option "port" expected int, got "x"`,
+}, {
+	about: "overflow",
+	out: &struct {
+		Port int8 `charm:"port"`
+	}{},
+	settings: charm.Settings{"port": 1000},
+	expect:   `cannot set field Port from option "port": value 1000 overflows int8`,
+}, {
+	about: "duplicate tag",
+	out: &struct {
+		A string `charm:"name"`
+		B string `charm:"name"`
+	}{},
+	expect: `fields A and B are both tagged with option "name"`,
+}}
+
+func (s *ConfigDecodeSuite) TestDecodeErrors(c *gc.C) {
+	config, err := charm.ReadConfig(strings.NewReader(decodeConfig))
+	c.Assert(err, gc.IsNil)
+	for i, test := range decodeErrorTests {
+		c.Logf("test %d: %s", i, test.about)
+		err := config.Decode(test.settings, test.out)
+		c.Check(err, gc.ErrorMatches, test.expect)
+	}
+}
+
+type structConfig struct {
+	Name     string            `charm:"name" description:"The name."`
+	Password string            `charm:"password,secret"`
+	Port     int               `charm:"port"`
+	Ratio    float32           `charm:"ratio"`
+	Debug    bool              `charm:"debug"`
+	Timeout  time.Duration     `charm:"timeout"`
+	Cache    uint64            `charm:"cache,bytes"`
+	Ports    []int             `charm:"ports"`
+	Labels   map[string]string `charm:"labels"`
+	Other    string
+}
+
+func (s *ConfigDecodeSuite) TestConfigFromStruct(c *gc.C) {
+	config, err := charm.ConfigFromStruct(&structConfig{
+		Name:    "app",
+		Port:    80,
+		Timeout: time.Minute,
+		Cache:   1024,
+		Ports:   []int{80, 443},
+	})
+	c.Assert(err, gc.IsNil)
+	c.Assert(config.Options, jc.DeepEquals, map[string]charm.Option{
+		"name":     {Type: "string", Description: "The name.", Default: "app"},
+		"password": {Type: "secret"},
+		"port":     {Type: "int", Default: int64(80)},
+		"ratio":    {Type: "float"},
+		"debug":    {Type: "boolean"},
+		"timeout":  {Type: "duration", Default: time.Minute},
+		"cache":    {Type: "bytes", Default: int64(1024)},
+		"ports":    {Type: "list", Items: "int", Default: []interface{}{int64(80), int64(443)}},
+		"labels":   {Type: "map", Items: "string"},
+	})
+
+	// The generated config decodes into the struct it came from.
+	var out structConfig
+	err = config.Decode(charm.Settings{"password": "secret"}, &out)
+	c.Assert(err, gc.IsNil)
+	c.Assert(out, jc.DeepEquals, structConfig{
+		Name:     "app",
+		Password: "secret",
+		Port:     80,
+		Timeout:  time.Minute,
+		Cache:    1024,
+		Ports:    []int{80, 443},
+	})
+}
+
+func (s *ConfigDecodeSuite) TestConfigFromStructErrors(c *gc.C) {
+	_, err := charm.ConfigFromStruct(42)
+	c.Assert(err, gc.ErrorMatches, `cannot derive config from int: not a struct`)
+
+	_, err = charm.ConfigFromStruct(struct {
+		X []struct{} `charm:"x"`
+	}{})
+	c.Assert(err, gc.ErrorMatches, `field X of type \[\]struct {} cannot be used for an option of type ""`)
+
+	_, err = charm.ConfigFromStruct(struct {
+		X int `charm:"x,string"`
+	}{})
+	c.Assert(err, gc.ErrorMatches, `field X of type int cannot be used for an option of type "string"`)
+
+	_, err = charm.ConfigFromStruct(struct {
+		X string `charm:"x,enum"`
+	}{})
+	c.Assert(err, gc.ErrorMatches, `field X cannot be used for an enum option: the allowed values are unknown`)
+
+	_, err = charm.ConfigFromStruct(struct {
+		x string `charm:"x"`
+	}{})
+	c.Assert(err, gc.ErrorMatches, `field x is not exported`)
+
+	_, err = charm.ConfigFromStruct(struct {
+		X uint64 `charm:"x"`
+	}{math.MaxUint64})
+	c.Assert(err, gc.ErrorMatches, `invalid default for field X: value 18446744073709551615 overflows int64`)
+}
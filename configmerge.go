@@ -0,0 +1,105 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package charm
+
+import (
+	"github.com/juju/errors"
+)
+
+// ResetSetting may be used as the value of a setting in a layer passed
+// to Config.Merge to reset the option to its default value, discarding
+// any value supplied by an earlier layer.
+var ResetSetting interface{} = resetSetting{}
+
+type resetSetting struct{}
+
+// DefaultLayer is the source recorded by Config.Merge for
+// settings that hold the default value of their option.
+const DefaultLayer = -1
+
+// MergedSettings holds the result of Config.Merge.
+type MergedSettings struct {
+	// Settings holds the final value of every option.
+	Settings Settings
+
+	// Sources holds the index of the layer that supplied the
+	// final value of every option, or DefaultLayer if the value
+	// is the option's default.
+	Sources map[string]int
+
+	// Warnings holds the deprecation warnings for the settings
+	// in all the layers, as returned by MigrateSettings.
+	Warnings []DeprecationWarning
+}
+
+// Merge combines the given layers of settings, such as those from a
+// bundle, an overlay and the user, on top of the default settings.
+// Each layer takes precedence over the layers before it. A setting
+// with the value ResetSetting resets its option to the default.
+//
+// Each layer is migrated as by MigrateSettings and validated as by
+// ValidateSettings, except that the requires and conflicts-with
+// constraints are checked only against the merged settings. An error
+// in a layer is annotated with the layer's index; its cause is a
+// *SettingsError.
+func (c *Config) Merge(layers ...Settings) (*MergedSettings, error) {
+	merged := &MergedSettings{
+		Settings: c.DefaultSettings(),
+		Sources:  make(map[string]int),
+	}
+	for name := range merged.Settings {
+		merged.Sources[name] = DefaultLayer
+	}
+	for i, layer := range layers {
+		layer, warnings := c.MigrateSettings(layer)
+		merged.Warnings = append(merged.Warnings, warnings...)
+		values := make(Settings)
+		var reset []string
+		for name, value := range layer {
+			if value == ResetSetting {
+				reset = append(reset, name)
+			} else {
+				values[name] = value
+			}
+		}
+		checker := &settingsChecker{config: c}
+		values = checker.checkValues(values)
+		for _, name := range reset {
+			if _, err := c.option(name); err != nil {
+				checker.addError(name, err)
+			}
+		}
+		if err := checker.err(); err != nil {
+			return nil, errors.Annotatef(err, "layer %d", i)
+		}
+		for name, value := range values {
+			merged.Settings[name] = value
+			merged.Sources[name] = i
+		}
+		for _, name := range reset {
+			merged.Settings[name] = c.Options[name].Default
+			merged.Sources[name] = DefaultLayer
+		}
+	}
+
+	// Defaults never violate the requires and conflicts-with
+	// constraints, so only the values from layers are checked.
+	checker := &settingsChecker{config: c}
+	checker.checkRelated(merged.layerSettings())
+	if err := checker.err(); err != nil {
+		return nil, err
+	}
+	return merged, nil
+}
+
+// layerSettings returns the settings whose values were supplied by a layer.
+func (m *MergedSettings) layerSettings() Settings {
+	settings := make(Settings)
+	for name, value := range m.Settings {
+		if m.Sources[name] != DefaultLayer {
+			settings[name] = value
+		}
+	}
+	return settings
+}
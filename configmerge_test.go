@@ -0,0 +1,98 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package charm_test
+
+import (
+	"strings"
+
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"gopkg.in/juju/charm.v6"
+)
+
+type ConfigMergeSuite struct {
+	config *charm.Config
+}
+
+var _ = gc.Suite(&ConfigMergeSuite{})
+
+func (s *ConfigMergeSuite) SetUpTest(c *gc.C) {
+	var err error
+	s.config, err = charm.ReadConfig(strings.NewReader(`
+options:
+    name:
+        type: string
+        default: app
+    port:
+        type: int
+        default: 80
+        maximum: 1024
+    debug:
+        type: boolean
+    tls-cert:
+        type: string
+        requires: [tls-key]
+    tls-key:
+        type: string
+`))
+	c.Assert(err, gc.IsNil)
+}
+
+func (s *ConfigMergeSuite) TestMergeNoLayers(c *gc.C) {
+	merged, err := s.config.Merge()
+	c.Assert(err, gc.IsNil)
+	c.Assert(merged.Settings, jc.DeepEquals, s.config.DefaultSettings())
+	c.Assert(merged.Sources, jc.DeepEquals, map[string]int{
+		"name":     charm.DefaultLayer,
+		"port":     charm.DefaultLayer,
+		"debug":    charm.DefaultLayer,
+		"tls-cert": charm.DefaultLayer,
+		"tls-key":  charm.DefaultLayer,
+	})
+}
+
+func (s *ConfigMergeSuite) TestMergeLayers(c *gc.C) {
+	merged, err := s.config.Merge(
+		charm.Settings{"port": 80, "debug": true, "tls-key": "key"},
+		charm.Settings{"port": 443, "name": "web"},
+		charm.Settings{"name": charm.ResetSetting, "tls-cert": "cert"},
+	)
+	c.Assert(err, gc.IsNil)
+	c.Assert(merged.Settings, jc.DeepEquals, charm.Settings{
+		"name":     "app",
+		"port":     int64(443),
+		"debug":    true,
+		"tls-cert": "cert",
+		"tls-key":  "key",
+	})
+	c.Assert(merged.Sources, jc.DeepEquals, map[string]int{
+		"name":     charm.DefaultLayer,
+		"port":     1,
+		"debug":    0,
+		"tls-cert": 2,
+		"tls-key":  0,
+	})
+}
+
+func (s *ConfigMergeSuite) TestMergeInvalidLayer(c *gc.C) {
+	_, err := s.config.Merge(
+		charm.Settings{"port": 8},
+		charm.Settings{"port": 2048, "unknown": charm.ResetSetting},
+	)
+	c.Assert(err, gc.ErrorMatches, `layer 1: option "port" must be at most 1024, got 2048 \(and 1 more errors\)`)
+	settingsErr, ok := errors.Cause(err).(*charm.SettingsError)
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(settingsErr.Errors, gc.HasLen, 2)
+	c.Assert(settingsErr.Errors[1], gc.ErrorMatches, `unknown option "unknown"`)
+}
+
+func (s *ConfigMergeSuite) TestMergeChecksRelatedOptionsAcrossLayers(c *gc.C) {
+	_, err := s.config.Merge(
+		charm.Settings{"tls-key": "key"},
+		charm.Settings{"tls-cert": "cert", "tls-key": charm.ResetSetting},
+	)
+	c.Assert(err, gc.ErrorMatches, `option "tls-cert" requires option "tls-key" to be set`)
+}
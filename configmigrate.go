@@ -0,0 +1,104 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package charm
+
+import (
+	"fmt"
+	"sort"
+)
+
+// DeprecationWarning describes a setting for a deprecated
+// or renamed option found by Config.MigrateSettings.
+type DeprecationWarning struct {
+	// Option holds the name of the option.
+	Option string
+
+	// RenamedTo holds the name of the option that the setting
+	// was migrated to, or is empty if the option is deprecated
+	// but has not been renamed.
+	RenamedTo string
+}
+
+func (w DeprecationWarning) String() string {
+	if w.RenamedTo != "" {
+		return fmt.Sprintf("option %q has been renamed to %q", w.Option, w.RenamedTo)
+	}
+	return fmt.Sprintf("option %q is deprecated", w.Option)
+}
+
+// MigrateSettings returns a copy of the supplied settings in which the
+// settings for renamed options have been moved to the options that
+// replace them, following any chain of renames. When the settings hold
+// values for both the old and new names, the value for the new name is
+// kept. It also returns a warning, sorted by option name, for each
+// setting of a renamed or deprecated option. Unknown settings are left
+// unchanged.
+func (c *Config) MigrateSettings(settings Settings) (Settings, []DeprecationWarning) {
+	out := make(Settings)
+	var warnings []DeprecationWarning
+	var renamed []string
+	for name, value := range settings {
+		option, ok := c.Options[name]
+		switch {
+		case !ok:
+		case option.RenamedTo != "":
+			renamed = append(renamed, name)
+			warnings = append(warnings, DeprecationWarning{
+				Option:    name,
+				RenamedTo: c.renamedOption(name),
+			})
+			continue
+		case option.Deprecated:
+			warnings = append(warnings, DeprecationWarning{Option: name})
+		}
+		out[name] = value
+	}
+	// Migrate in order, so that the result does not depend on
+	// map ordering when several old names map to the same option.
+	sort.Strings(renamed)
+	for _, name := range renamed {
+		target := c.renamedOption(name)
+		if _, ok := out[target]; !ok {
+			out[target] = settings[name]
+		}
+	}
+	sort.Slice(warnings, func(i, j int) bool {
+		return warnings[i].Option < warnings[j].Option
+	})
+	return out, warnings
+}
+
+// renamedOption returns the name of the option that finally
+// replaces the named option, which may be the option itself.
+func (c *Config) renamedOption(name string) string {
+	// The chain is checked by ReadConfig, but guard
+	// against cycles in configs built by hand.
+	for i := 0; i <= len(c.Options); i++ {
+		next := c.Options[name].RenamedTo
+		if next == "" {
+			break
+		}
+		name = next
+	}
+	return name
+}
+
+// checkRename checks that the named option is not renamed to
+// an unknown option or to itself, directly or indirectly.
+func (c *Config) checkRename(name string, option Option) error {
+	if option.RenamedTo == "" {
+		return nil
+	}
+	seen := map[string]bool{name: true}
+	for next := option.RenamedTo; next != ""; next = c.Options[next].RenamedTo {
+		if _, ok := c.Options[next]; !ok {
+			return fmt.Errorf("option %q is renamed to unknown option %q", name, next)
+		}
+		if seen[next] {
+			return fmt.Errorf("option %q has a cycle of renames", name)
+		}
+		seen[next] = true
+	}
+	return nil
+}
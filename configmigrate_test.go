@@ -0,0 +1,157 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package charm_test
+
+import (
+	"strings"
+
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"gopkg.in/juju/charm.v6"
+)
+
+type ConfigMigrateSuite struct {
+	config *charm.Config
+}
+
+var _ = gc.Suite(&ConfigMigrateSuite{})
+
+func (s *ConfigMigrateSuite) SetUpTest(c *gc.C) {
+	var err error
+	s.config, err = charm.ReadConfig(strings.NewReader(`
+options:
+    loglevel:
+        type: string
+        renamed-to: log_level
+    log_level:
+        type: string
+        renamed-to: log-level
+    log-level:
+        type: string
+        default: info
+    port:
+        type: int
+    legacy-mode:
+        type: boolean
+        deprecated: true
+`))
+	c.Assert(err, gc.IsNil)
+}
+
+func (s *ConfigMigrateSuite) TestMigrateSettings(c *gc.C) {
+	settings, warnings := s.config.MigrateSettings(charm.Settings{
+		"loglevel":    "debug",
+		"port":        80,
+		"legacy-mode": true,
+		"unknown":     "x",
+	})
+	c.Assert(settings, jc.DeepEquals, charm.Settings{
+		"log-level":   "debug",
+		"port":        80,
+		"legacy-mode": true,
+		"unknown":     "x",
+	})
+	c.Assert(warnings, jc.DeepEquals, []charm.DeprecationWarning{
+		{Option: "legacy-mode"},
+		{Option: "loglevel", RenamedTo: "log-level"},
+	})
+	c.Assert(warnings[0].String(), gc.Equals, `option "legacy-mode" is deprecated`)
+	c.Assert(warnings[1].String(), gc.Equals, `option "loglevel" has been renamed to "log-level"`)
+}
+
+func (s *ConfigMigrateSuite) TestMigrateSettingsNewNameWins(c *gc.C) {
+	settings, warnings := s.config.MigrateSettings(charm.Settings{
+		"loglevel":  "debug",
+		"log_level": "warning",
+		"log-level": "error",
+	})
+	c.Assert(settings, jc.DeepEquals, charm.Settings{"log-level": "error"})
+	c.Assert(warnings, gc.HasLen, 2)
+
+	settings, _ = s.config.MigrateSettings(charm.Settings{
+		"loglevel":  "debug",
+		"log_level": "warning",
+	})
+	c.Assert(settings, jc.DeepEquals, charm.Settings{"log-level": "warning"})
+}
+
+func (s *ConfigMigrateSuite) TestValidateAndFilterSettingsMigrate(c *gc.C) {
+	settings, err := s.config.ValidateSettings(charm.Settings{"loglevel": "debug"})
+	c.Assert(err, gc.IsNil)
+	c.Assert(settings, jc.DeepEquals, charm.Settings{"log-level": "debug"})
+
+	_, err = s.config.ValidateSettings(charm.Settings{"loglevel": 42})
+	c.Assert(err, gc.ErrorMatches, `option "log-level" expected string, got 42`)
+
+	settings = s.config.FilterSettings(charm.Settings{"log_level": "debug", "port": "x"})
+	c.Assert(settings, jc.DeepEquals, charm.Settings{"log-level": "debug"})
+}
+
+func (s *ConfigMigrateSuite) TestValidateAndFilterSettingsWithWarnings(c *gc.C) {
+	settings, warnings, err := s.config.ValidateSettingsWithWarnings(charm.Settings{
+		"loglevel":    "debug",
+		"legacy-mode": true,
+	})
+	c.Assert(err, gc.IsNil)
+	c.Assert(settings, jc.DeepEquals, charm.Settings{
+		"log-level":   "debug",
+		"legacy-mode": true,
+	})
+	c.Assert(warnings, jc.DeepEquals, []charm.DeprecationWarning{
+		{Option: "legacy-mode"},
+		{Option: "loglevel", RenamedTo: "log-level"},
+	})
+
+	// Warnings are returned along with any error.
+	settings, warnings, err = s.config.ValidateSettingsWithWarnings(charm.Settings{"loglevel": 42})
+	c.Assert(err, gc.ErrorMatches, `option "log-level" expected string, got 42`)
+	c.Assert(settings, gc.IsNil)
+	c.Assert(warnings, jc.DeepEquals, []charm.DeprecationWarning{
+		{Option: "loglevel", RenamedTo: "log-level"},
+	})
+
+	settings, warnings = s.config.FilterSettingsWithWarnings(charm.Settings{"log_level": "debug", "port": "x"})
+	c.Assert(settings, jc.DeepEquals, charm.Settings{"log-level": "debug"})
+	c.Assert(warnings, jc.DeepEquals, []charm.DeprecationWarning{
+		{Option: "log_level", RenamedTo: "log-level"},
+	})
+}
+
+func (s *ConfigMigrateSuite) TestDefaultSettingsOmitRenamedOptions(c *gc.C) {
+	c.Assert(s.config.DefaultSettings(), jc.DeepEquals, charm.Settings{
+		"log-level":   "info",
+		"port":        nil,
+		"legacy-mode": nil,
+	})
+}
+
+func (s *ConfigMigrateSuite) TestMergeMigratesLayers(c *gc.C) {
+	merged, err := s.config.Merge(
+		charm.Settings{"loglevel": "debug"},
+		charm.Settings{"legacy-mode": true},
+	)
+	c.Assert(err, gc.IsNil)
+	c.Assert(merged.Settings["log-level"], gc.Equals, "debug")
+	c.Assert(merged.Sources["log-level"], gc.Equals, 0)
+	c.Assert(merged.Warnings, jc.DeepEquals, []charm.DeprecationWarning{
+		{Option: "loglevel", RenamedTo: "log-level"},
+		{Option: "legacy-mode"},
+	})
+}
+
+func (s *ConfigMigrateSuite) TestInvalidRenames(c *gc.C) {
+	_, err := charm.ReadConfig(strings.NewReader(`options: {a: {type: string, renamed-to: b}}`))
+	c.Assert(err, gc.ErrorMatches, `invalid config: option "a" is renamed to unknown option "b"`)
+
+	_, err = charm.ReadConfig(strings.NewReader(`options: {a: {type: string, renamed-to: a}}`))
+	c.Assert(err, gc.ErrorMatches, `invalid config: option "a" has a cycle of renames`)
+
+	_, err = charm.ReadConfig(strings.NewReader(`
+options:
+    a: {type: string, renamed-to: b}
+    b: {type: string, renamed-to: a}
+`))
+	c.Assert(err, gc.ErrorMatches, `invalid config: option "[ab]" has a cycle of renames`)
+}
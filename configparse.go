@@ -0,0 +1,168 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package charm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// normalizeOptionName returns the form of an option name
+// used to match names that differ only in case or in the
+// use of dashes and underscores.
+func normalizeOptionName(name string) string {
+	return strings.ToLower(strings.Replace(name, "_", "-", -1))
+}
+
+// resolveOption returns the name of the option that the given name
+// refers to. A name refers to the option with exactly that name or,
+// if there is none, to the only option whose name differs from it
+// only in case or in the use of dashes and underscores.
+func (c *Config) resolveOption(name string) (string, error) {
+	if _, ok := c.Options[name]; ok {
+		return name, nil
+	}
+	var found []string
+	normalized := normalizeOptionName(name)
+	for option := range c.Options {
+		if normalizeOptionName(option) == normalized {
+			found = append(found, option)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("unknown option %q", name)
+	case 1:
+		return found[0], nil
+	}
+	return "", fmt.Errorf("ambiguous option name %q", name)
+}
+
+// parseSetting returns the option name and value
+// of a setting given as a name and a string value.
+func (c *Config) parseSetting(name, str string) (string, interface{}, error) {
+	name, err := c.resolveOption(name)
+	if err != nil {
+		return "", nil, err
+	}
+	option := c.Options[name]
+	value, err := option.parse(name, str)
+	if err != nil {
+		if option.Type == "secret" {
+			// Never include the value of a secret in an error.
+			return "", nil, fmt.Errorf("option %q has invalid value %s", name, RedactedValue)
+		}
+		return "", nil, err
+	}
+	return name, value, nil
+}
+
+// ParseSettingsPairs returns the settings given by a list of name=value
+// pairs, as supplied to a command line flag such as --set. Names may use
+// dashes and underscores interchangeably and are not case-sensitive.
+// Values are parsed as by ParseSettingsStrings; when an option is given
+// more than once, the last value is used. Errors identify a setting by
+// its name or position, never by the whole pair, so that secret values
+// are not disclosed.
+func (c *Config) ParseSettingsPairs(pairs []string) (Settings, error) {
+	out := make(Settings)
+	for i, pair := range pairs {
+		parts := strings.SplitN(pair, "=", 2)
+		name := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || name == "" {
+			return nil, fmt.Errorf("invalid setting #%d: expected name=value", i+1)
+		}
+		name, value, err := c.parseSetting(name, parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid setting %q: %v", strings.TrimSpace(parts[0]), err)
+		}
+		out[name] = value
+	}
+	return out, nil
+}
+
+// ParseSettingsEnvFile returns the settings held in the given .env file
+// content. Each line holds a NAME=value assignment, optionally preceded
+// by "export"; blank lines and lines starting with # are ignored. Values
+// may be enclosed in single quotes, which are taken literally, or in
+// double quotes, in which the escapes of Go string literals are
+// interpreted. Names are matched as by ParseSettingsPairs. Errors are
+// reported with the number of the offending line.
+func (c *Config) ParseSettingsEnvFile(r io.Reader) (Settings, error) {
+	out := make(Settings)
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, str, err := parseEnvLine(line)
+		if err == nil {
+			var value interface{}
+			if name, value, err = c.parseSetting(name, str); err == nil {
+				out[name] = value
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNum, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// parseEnvLine returns the name and unquoted value
+// of the assignment on a line of a .env file.
+func parseEnvLine(line string) (name, value string, err error) {
+	if strings.HasPrefix(line, "export ") || strings.HasPrefix(line, "export\t") {
+		line = strings.TrimSpace(line[len("export"):])
+	}
+	parts := strings.SplitN(line, "=", 2)
+	name = strings.TrimSpace(parts[0])
+	if len(parts) != 2 || name == "" || strings.ContainsAny(name, " \t") {
+		return "", "", fmt.Errorf("expected NAME=value")
+	}
+	value = strings.TrimSpace(parts[1])
+	switch {
+	case len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"':
+		if value, err = strconv.Unquote(value); err != nil {
+			return "", "", fmt.Errorf("invalid quoted value for %s", name)
+		}
+	case len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'':
+		value = value[1 : len(value)-1]
+	case strings.HasPrefix(value, `"`) || strings.HasPrefix(value, "'"):
+		return "", "", fmt.Errorf("unterminated quoted value for %s", name)
+	}
+	return name, value, nil
+}
+
+// ParseSettingsEnv returns the settings held in the environment variables
+// with the given prefix, such as "CHARM_CFG_". The environment is given
+// in the form returned by os.Environ. The option named by a variable is
+// the remainder of its name, matched as by ParseSettingsPairs, so that
+// CHARM_CFG_LOG_LEVEL sets the log-level option. Variables without the
+// prefix are ignored.
+func (c *Config) ParseSettingsEnv(environ []string, prefix string) (Settings, error) {
+	if prefix == "" {
+		return nil, fmt.Errorf("empty environment variable prefix")
+	}
+	out := make(Settings)
+	for _, env := range environ {
+		parts := strings.SplitN(env, "=", 2)
+		if len(parts) != 2 || !strings.HasPrefix(parts[0], prefix) {
+			continue
+		}
+		name, value, err := c.parseSetting(strings.TrimPrefix(parts[0], prefix), parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("environment variable %s: %v", parts[0], err)
+		}
+		out[name] = value
+	}
+	return out, nil
+}
@@ -0,0 +1,164 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package charm_test
+
+import (
+	"strings"
+
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"gopkg.in/juju/charm.v6"
+)
+
+type ConfigParseSuite struct {
+	config *charm.Config
+}
+
+var _ = gc.Suite(&ConfigParseSuite{})
+
+func (s *ConfigParseSuite) SetUpTest(c *gc.C) {
+	var err error
+	s.config, err = charm.ReadConfig(strings.NewReader(`
+options:
+    log-level:
+        type: string
+    port:
+        type: int
+    debug:
+        type: boolean
+    max_conns:
+        type: int
+`))
+	c.Assert(err, gc.IsNil)
+}
+
+func (s *ConfigParseSuite) TestParseSettingsPairs(c *gc.C) {
+	settings, err := s.config.ParseSettingsPairs([]string{
+		"log_level=debug",
+		"port=80",
+		"PORT=8080",
+		"max-conns=10",
+		"debug=true",
+	})
+	c.Assert(err, gc.IsNil)
+	c.Assert(settings, jc.DeepEquals, charm.Settings{
+		"log-level": "debug",
+		"port":      int64(8080),
+		"max_conns": int64(10),
+		"debug":     true,
+	})
+}
+
+func (s *ConfigParseSuite) TestParseSettingsPairsErrors(c *gc.C) {
+	_, err := s.config.ParseSettingsPairs([]string{"port=80", "debug"})
+	c.Assert(err, gc.ErrorMatches, `invalid setting #2: expected name=value`)
+
+	_, err = s.config.ParseSettingsPairs([]string{"=1"})
+	c.Assert(err, gc.ErrorMatches, `invalid setting #1: expected name=value`)
+
+	_, err = s.config.ParseSettingsPairs([]string{"colour=red"})
+	c.Assert(err, gc.ErrorMatches, `invalid setting "colour": unknown option "colour"`)
+
+	_, err = s.config.ParseSettingsPairs([]string{"port=http"})
+	c.Assert(err, gc.ErrorMatches, `invalid setting "port": option "port" expected int, got "http"`)
+}
+
+func (s *ConfigParseSuite) TestParseSettingsPairsErrorsHideValues(c *gc.C) {
+	config := &charm.Config{Options: map[string]charm.Option{
+		"password": {Type: "secret"},
+	}}
+	_, err := config.ParseSettingsPairs([]string{"Password=hunter2", "hunter2"})
+	c.Assert(err, gc.ErrorMatches, `invalid setting #2: expected name=value`)
+
+	_, err = config.ParseSettingsPairs([]string{"passwd=hunter2"})
+	c.Assert(err, gc.ErrorMatches, `invalid setting "passwd": unknown option "passwd"`)
+}
+
+func (s *ConfigParseSuite) TestAmbiguousName(c *gc.C) {
+	config := &charm.Config{Options: map[string]charm.Option{
+		"a-b": {Type: "string"},
+		"a_b": {Type: "string"},
+	}}
+	settings, err := config.ParseSettingsPairs([]string{"a_b=x"})
+	c.Assert(err, gc.IsNil)
+	c.Assert(settings, jc.DeepEquals, charm.Settings{"a_b": "x"})
+
+	_, err = config.ParseSettingsPairs([]string{"A-B=x"})
+	c.Assert(err, gc.ErrorMatches, `invalid setting "A-B": ambiguous option name "A-B"`)
+}
+
+func (s *ConfigParseSuite) TestParseSettingsEnvFile(c *gc.C) {
+	settings, err := s.config.ParseSettingsEnvFile(strings.NewReader(`
+# A comment.
+LOG_LEVEL="with \"quotes\"\tand tab"
+
+export PORT=8080
+MAX_CONNS = '10'
+debug=true
+`))
+	c.Assert(err, gc.IsNil)
+	c.Assert(settings, jc.DeepEquals, charm.Settings{
+		"log-level": "with \"quotes\"\tand tab",
+		"port":      int64(8080),
+		"max_conns": int64(10),
+		"debug":     true,
+	})
+}
+
+var envFileErrorTests = []struct {
+	about  string
+	input  string
+	expect string
+}{{
+	about:  "missing assignment",
+	input:  "PORT=80\n\nDEBUG\n",
+	expect: `line 3: expected NAME=value`,
+}, {
+	about:  "unknown option",
+	input:  "# comment\nCOLOUR=red\n",
+	expect: `line 2: unknown option "COLOUR"`,
+}, {
+	about:  "invalid value",
+	input:  "PORT=http\n",
+	expect: `line 1: option "port" expected int, got "http"`,
+}, {
+	about:  "unterminated quote",
+	input:  "PORT=80\nLOG_LEVEL=\"debug\n",
+	expect: `line 2: unterminated quoted value for LOG_LEVEL`,
+}, {
+	about:  "invalid escape",
+	input:  `LOG_LEVEL="\q"`,
+	expect: `line 1: invalid quoted value for LOG_LEVEL`,
+}}
+
+func (s *ConfigParseSuite) TestParseSettingsEnvFileErrors(c *gc.C) {
+	for i, test := range envFileErrorTests {
+		c.Logf("test %d: %s", i, test.about)
+		_, err := s.config.ParseSettingsEnvFile(strings.NewReader(test.input))
+		c.Check(err, gc.ErrorMatches, test.expect)
+	}
+}
+
+func (s *ConfigParseSuite) TestParseSettingsEnv(c *gc.C) {
+	settings, err := s.config.ParseSettingsEnv([]string{
+		"HOME=/root",
+		"CHARM_CFG_LOG_LEVEL=info",
+		"CHARM_CFG_PORT=80",
+		"CHARM_CFG_MAX_CONNS=5",
+		"CHARM_OTHER=x",
+	}, "CHARM_CFG_")
+	c.Assert(err, gc.IsNil)
+	c.Assert(settings, jc.DeepEquals, charm.Settings{
+		"log-level": "info",
+		"port":      int64(80),
+		"max_conns": int64(5),
+	})
+
+	_, err = s.config.ParseSettingsEnv([]string{"CHARM_CFG_PORT=x"}, "CHARM_CFG_")
+	c.Assert(err, gc.ErrorMatches, `environment variable CHARM_CFG_PORT: option "port" expected int, got "x"`)
+
+	_, err = s.config.ParseSettingsEnv(nil, "")
+	c.Assert(err, gc.ErrorMatches, `empty environment variable prefix`)
+}
@@ -0,0 +1,35 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package charm
+
+// ContentDigest returns the hex-encoded SHA-256 digest of the
+// canonical content of the archive that ArchiveTo would write.
+//
+// The digest depends only on the names, kinds and contents of the
+// files and symbolic links in the archive: directories, entry order,
+// timestamps and permissions other than the executable bit are
+// ignored. It is equal to the ContentDigest of the CharmArchive read
+// from any archive written by ArchiveTo or ArchiveToWithOptions, so
+// two builds may be compared by their digests.
+//
+// The digest is computed from the charm's FileManifest.
+func (dir *CharmDir) ContentDigest() (string, error) {
+	m, err := dir.FileManifest()
+	if err != nil {
+		return "", err
+	}
+	return m.ContentDigest(), nil
+}
+
+// ContentDigest returns the hex-encoded SHA-256 digest of the
+// canonical content of the archive. See CharmDir.ContentDigest
+// for details. The signature entry added by SignCharmArchive
+// is not included.
+func (a *CharmArchive) ContentDigest() (string, error) {
+	m, err := a.FileManifest()
+	if err != nil {
+		return "", err
+	}
+	return m.ContentDigest(), nil
+}
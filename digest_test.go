@@ -0,0 +1,74 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package charm_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"gopkg.in/juju/charm.v6"
+)
+
+type DigestSuite struct{}
+
+var _ = gc.Suite(&DigestSuite{})
+
+func (s *DigestSuite) readDir(c *gc.C, path string) *charm.CharmDir {
+	dir, err := charm.ReadCharmDir(path)
+	c.Assert(err, gc.IsNil)
+	return dir
+}
+
+func (s *DigestSuite) TestDirMatchesArchive(c *gc.C) {
+	dir := s.readDir(c, cloneDir(c, charmDirPath(c, "dummy")))
+	digest, err := dir.ContentDigest()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(digest, gc.Matches, "[0-9a-f]{64}")
+
+	for _, opts := range []charm.ArchiveOptions{{}, {Reproducible: true}} {
+		var buf bytes.Buffer
+		err := dir.ArchiveToWithOptions(&buf, opts)
+		c.Assert(err, jc.ErrorIsNil)
+		archive, err := charm.ReadCharmArchiveBytes(buf.Bytes())
+		c.Assert(err, jc.ErrorIsNil)
+		archiveDigest, err := archive.ContentDigest()
+		c.Assert(err, jc.ErrorIsNil)
+		c.Assert(archiveDigest, gc.Equals, digest, gc.Commentf("options %#v", opts))
+	}
+}
+
+func (s *DigestSuite) TestDigestDependsOnContent(c *gc.C) {
+	path := cloneDir(c, charmDirPath(c, "dummy"))
+	digest, err := s.readDir(c, path).ContentDigest()
+	c.Assert(err, jc.ErrorIsNil)
+
+	// Permission changes other than the executable bit
+	// do not affect the digest.
+	err = os.Chmod(filepath.Join(path, "config.yaml"), 0600)
+	c.Assert(err, jc.ErrorIsNil)
+	newDigest, err := s.readDir(c, path).ContentDigest()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(newDigest, gc.Equals, digest)
+
+	// The executable bit does.
+	err = os.Chmod(filepath.Join(path, "config.yaml"), 0700)
+	c.Assert(err, jc.ErrorIsNil)
+	newDigest, err = s.readDir(c, path).ContentDigest()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(newDigest, gc.Not(gc.Equals), digest)
+	err = os.Chmod(filepath.Join(path, "config.yaml"), 0644)
+	c.Assert(err, jc.ErrorIsNil)
+
+	// As does file content.
+	err = ioutil.WriteFile(filepath.Join(path, "src", "hello.c"), []byte("changed"), 0644)
+	c.Assert(err, jc.ErrorIsNil)
+	newDigest, err = s.readDir(c, path).ContentDigest()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(newDigest, gc.Not(gc.Equals), digest)
+}
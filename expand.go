@@ -0,0 +1,322 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package charm
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ExpandOptions holds limits that are enforced when expanding an
+// archive that may come from an untrusted source. A zero value for
+// any limit means that it is not enforced.
+type ExpandOptions struct {
+	// MaxTotalSize holds the maximum number of bytes
+	// that may be written in total.
+	MaxTotalSize int64
+
+	// MaxFiles holds the maximum number of entries, including
+	// directories and symbolic links, that the archive may hold.
+	MaxFiles int
+
+	// MaxFileSize holds the maximum uncompressed size
+	// of any single file.
+	MaxFileSize int64
+
+	// MaxCompressionRatio holds the maximum ratio of the uncompressed
+	// size of any file to its compressed size.
+	MaxCompressionRatio float64
+}
+
+// TotalSizeExceededError is returned when expanding an archive
+// would write more than ExpandOptions.MaxTotalSize bytes.
+type TotalSizeExceededError struct {
+	Max int64
+}
+
+func (err *TotalSizeExceededError) Error() string {
+	return fmt.Sprintf("archive expands to more than the maximum total size of %d bytes", err.Max)
+}
+
+// FileCountExceededError is returned when an archive holds
+// more than ExpandOptions.MaxFiles entries.
+type FileCountExceededError struct {
+	Count int
+	Max   int
+}
+
+func (err *FileCountExceededError) Error() string {
+	return fmt.Sprintf("archive holds %d files, more than the maximum of %d", err.Count, err.Max)
+}
+
+// FileSizeExceededError is returned when a file in an archive is
+// larger than ExpandOptions.MaxFileSize.
+type FileSizeExceededError struct {
+	Path string
+	Max  int64
+}
+
+func (err *FileSizeExceededError) Error() string {
+	return fmt.Sprintf("file %q is larger than the maximum size of %d bytes", err.Path, err.Max)
+}
+
+// CompressionRatioExceededError is returned when a file in an archive
+// is compressed more than ExpandOptions.MaxCompressionRatio allows.
+type CompressionRatioExceededError struct {
+	Path string
+	Max  float64
+}
+
+func (err *CompressionRatioExceededError) Error() string {
+	return fmt.Sprintf("file %q exceeds the maximum compression ratio of %g", err.Path, err.Max)
+}
+
+// PathEscapeError is returned when an archive entry would be
+// written outside the directory the archive is expanded into.
+type PathEscapeError struct {
+	Path string
+}
+
+func (err *PathEscapeError) Error() string {
+	return fmt.Sprintf("path %q escapes the target directory", err.Path)
+}
+
+// SymlinkEscapeError is returned when a symbolic link in an
+// archive is absolute or links out of the archive.
+type SymlinkEscapeError struct {
+	Path   string
+	Target string
+
+	// Err holds the reason the link was rejected.
+	Err error
+}
+
+func (err *SymlinkEscapeError) Error() string {
+	return err.Err.Error()
+}
+
+// expandArchive expands the contents of zipr into dir,
+// creating it if necessary and enforcing the given limits.
+// Entries are never written outside dir, and symbolic
+// links must not leave it.
+func expandArchive(zipr *zip.Reader, dir string, opts ExpandOptions) error {
+	if opts.MaxFiles > 0 && len(zipr.File) > opts.MaxFiles {
+		return &FileCountExceededError{
+			Count: len(zipr.File),
+			Max:   opts.MaxFiles,
+		}
+	}
+	x := &expander{
+		dir:       dir,
+		opts:      opts,
+		remaining: opts.MaxTotalSize,
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, f := range zipr.File {
+		if err := x.expand(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type expander struct {
+	dir  string
+	opts ExpandOptions
+
+	// remaining holds the number of bytes that may still be
+	// written when opts.MaxTotalSize is set.
+	remaining int64
+}
+
+func (x *expander) expand(f *zip.File) error {
+	name := path.Clean(f.Name)
+	if name == "." {
+		return nil
+	}
+	if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+		return &PathEscapeError{f.Name}
+	}
+	if err := x.checkParents(name); err != nil {
+		return err
+	}
+	target := filepath.Join(x.dir, filepath.FromSlash(name))
+	mode := f.Mode()
+	switch mode & os.ModeType {
+	case os.ModeDir:
+		return x.writeDir(target, mode.Perm())
+	case os.ModeSymlink:
+		return x.writeSymlink(target, name, f)
+	case 0:
+		return x.writeFile(target, name, f, mode.Perm())
+	}
+	return fmt.Errorf("file %q has an unknown type: %v", name, mode)
+}
+
+// checkParents checks that none of the existing parent directories
+// of the entry with the given name is a symbolic link, so that
+// the entry cannot be written outside the target directory.
+func (x *expander) checkParents(name string) error {
+	parts := strings.Split(name, "/")
+	p := x.dir
+	for _, part := range parts[:len(parts)-1] {
+		p = filepath.Join(p, part)
+		info, err := os.Lstat(p)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return &PathEscapeError{name}
+		}
+	}
+	return nil
+}
+
+func (x *expander) writeDir(target string, perm os.FileMode) error {
+	info, err := os.Lstat(target)
+	if err == nil && info.IsDir() {
+		return os.Chmod(target, perm)
+	}
+	if err := removeExisting(target); err != nil {
+		return err
+	}
+	return os.MkdirAll(target, perm)
+}
+
+func (x *expander) writeSymlink(target, name string, f *zip.File) error {
+	var buf bytes.Buffer
+	if err := x.copy(&buf, name, f); err != nil {
+		return err
+	}
+	linkTarget := buf.String()
+	if err := checkSymlinkTarget(x.dir, name, linkTarget); err != nil {
+		return &SymlinkEscapeError{
+			Path:   name,
+			Target: linkTarget,
+			Err:    err,
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	if err := removeExisting(target); err != nil {
+		return err
+	}
+	return os.Symlink(linkTarget, target)
+}
+
+func (x *expander) writeFile(target, name string, f *zip.File, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	if err := removeExisting(target); err != nil {
+		return err
+	}
+	w, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+	if err := x.copy(w, name, f); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// copy copies the content of f to w, enforcing the size limits.
+// The sizes recorded in the archive are checked first, but as they
+// cannot be trusted, the limits are also enforced on the data as it
+// is decompressed.
+func (x *expander) copy(w io.Writer, name string, f *zip.File) error {
+	size := int64(f.UncompressedSize64)
+	if err := x.checkSize(name, size, int64(f.CompressedSize64)); err != nil {
+		return err
+	}
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	var src io.Reader = r
+	// Read one byte more than any limit allows, so that
+	// data beyond a limit can be detected.
+	if limit := x.limit(int64(f.CompressedSize64)); limit >= 0 {
+		src = io.LimitReader(r, limit+1)
+	}
+	n, err := io.Copy(w, src)
+	if err != nil {
+		return err
+	}
+	if err := x.checkSize(name, n, int64(f.CompressedSize64)); err != nil {
+		return err
+	}
+	if x.opts.MaxTotalSize > 0 {
+		x.remaining -= n
+	}
+	return nil
+}
+
+// checkSize checks that a file with the given name, uncompressed
+// size and compressed size may be written.
+func (x *expander) checkSize(name string, size, compressedSize int64) error {
+	if x.opts.MaxFileSize > 0 && size > x.opts.MaxFileSize {
+		return &FileSizeExceededError{
+			Path: name,
+			Max:  x.opts.MaxFileSize,
+		}
+	}
+	if x.opts.MaxCompressionRatio > 0 && size > 0 {
+		if compressedSize <= 0 || float64(size) > x.opts.MaxCompressionRatio*float64(compressedSize) {
+			return &CompressionRatioExceededError{
+				Path: name,
+				Max:  x.opts.MaxCompressionRatio,
+			}
+		}
+	}
+	if x.opts.MaxTotalSize > 0 && size > x.remaining {
+		return &TotalSizeExceededError{x.opts.MaxTotalSize}
+	}
+	return nil
+}
+
+// limit returns the largest number of bytes that may be written
+// for a file with the given compressed size, or -1 if there is
+// no limit.
+func (x *expander) limit(compressedSize int64) int64 {
+	limit := int64(-1)
+	lower := func(n int64) {
+		if limit < 0 || n < limit {
+			limit = n
+		}
+	}
+	if x.opts.MaxFileSize > 0 {
+		lower(x.opts.MaxFileSize)
+	}
+	if x.opts.MaxTotalSize > 0 {
+		lower(x.remaining)
+	}
+	if x.opts.MaxCompressionRatio > 0 {
+		lower(int64(x.opts.MaxCompressionRatio * float64(compressedSize)))
+	}
+	return limit
+}
+
+// removeExisting removes anything at the given path.
+func removeExisting(path string) error {
+	if _, err := os.Lstat(path); os.IsNotExist(err) {
+		return nil
+	}
+	return os.RemoveAll(path)
+}
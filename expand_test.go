@@ -0,0 +1,166 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package charm_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"gopkg.in/juju/charm.v6"
+)
+
+type ExpandSuite struct{}
+
+var _ = gc.Suite(&ExpandSuite{})
+
+type zipEntry struct {
+	name string
+	mode os.FileMode
+	data string
+}
+
+const expandMeta = `
+name: expand
+summary: expand
+description: expand
+`
+
+// makeCharmZip returns a charm archive holding a metadata.yaml
+// file followed by the given entries.
+func makeCharmZip(c *gc.C, entries ...zipEntry) *charm.CharmArchive {
+	var buf bytes.Buffer
+	zipw := zip.NewWriter(&buf)
+	entries = append([]zipEntry{{name: "metadata.yaml", mode: 0644, data: expandMeta}}, entries...)
+	for _, e := range entries {
+		h := &zip.FileHeader{
+			Name:   e.name,
+			Method: zip.Deflate,
+		}
+		h.SetMode(e.mode)
+		w, err := zipw.CreateHeader(h)
+		c.Assert(err, jc.ErrorIsNil)
+		_, err = w.Write([]byte(e.data))
+		c.Assert(err, jc.ErrorIsNil)
+	}
+	c.Assert(zipw.Close(), jc.ErrorIsNil)
+	archive, err := charm.ReadCharmArchiveBytes(buf.Bytes())
+	c.Assert(err, jc.ErrorIsNil)
+	return archive
+}
+
+func (s *ExpandSuite) TestExpandCharm(c *gc.C) {
+	archive, err := charm.ReadCharmArchive(archivePath(c, readCharmDir(c, "dummy")))
+	c.Assert(err, jc.ErrorIsNil)
+	dir := filepath.Join(c.MkDir(), "charm")
+	err = archive.ExpandToWithOptions(dir, charm.ExpandOptions{
+		MaxTotalSize:        1 << 20,
+		MaxFiles:            100,
+		MaxFileSize:         1 << 16,
+		MaxCompressionRatio: 100,
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	expanded, err := charm.ReadCharmDir(dir)
+	c.Assert(err, jc.ErrorIsNil)
+	checkDummy(c, expanded, dir)
+	info, err := os.Stat(filepath.Join(dir, "hooks", "install"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(info.Mode()&0100, gc.Not(gc.Equals), os.FileMode(0))
+}
+
+func (s *ExpandSuite) TestFileCount(c *gc.C) {
+	archive := makeCharmZip(c,
+		zipEntry{name: "a", mode: 0644},
+		zipEntry{name: "b", mode: 0644},
+	)
+	err := archive.ExpandToWithOptions(c.MkDir(), charm.ExpandOptions{MaxFiles: 2})
+	c.Assert(err, gc.ErrorMatches, "archive holds 3 files, more than the maximum of 2")
+	c.Assert(err, jc.DeepEquals, &charm.FileCountExceededError{Count: 3, Max: 2})
+}
+
+func (s *ExpandSuite) TestFileSize(c *gc.C) {
+	archive := makeCharmZip(c, zipEntry{name: "big", mode: 0644, data: strings.Repeat("x", 1000)})
+	err := archive.ExpandToWithOptions(c.MkDir(), charm.ExpandOptions{MaxFileSize: 999})
+	c.Assert(err, gc.ErrorMatches, `file "big" is larger than the maximum size of 999 bytes`)
+	c.Assert(err, jc.DeepEquals, &charm.FileSizeExceededError{Path: "big", Max: 999})
+}
+
+func (s *ExpandSuite) TestTotalSize(c *gc.C) {
+	archive := makeCharmZip(c,
+		zipEntry{name: "a", mode: 0644, data: strings.Repeat("x", 100)},
+		zipEntry{name: "b", mode: 0644, data: strings.Repeat("x", 100)},
+	)
+	err := archive.ExpandToWithOptions(c.MkDir(), charm.ExpandOptions{
+		MaxTotalSize: int64(len(expandMeta) + 150),
+	})
+	c.Assert(err, gc.ErrorMatches, `archive expands to more than the maximum total size of \d+ bytes`)
+	c.Assert(err, gc.FitsTypeOf, &charm.TotalSizeExceededError{})
+}
+
+func (s *ExpandSuite) TestCompressionRatio(c *gc.C) {
+	archive := makeCharmZip(c, zipEntry{name: "bomb", mode: 0644, data: strings.Repeat("\x00", 100000)})
+	err := archive.ExpandToWithOptions(c.MkDir(), charm.ExpandOptions{MaxCompressionRatio: 10})
+	c.Assert(err, gc.ErrorMatches, `file "bomb" exceeds the maximum compression ratio of 10`)
+	c.Assert(err, jc.DeepEquals, &charm.CompressionRatioExceededError{Path: "bomb", Max: 10})
+}
+
+func (s *ExpandSuite) TestPathEscape(c *gc.C) {
+	for _, name := range []string{"../evil", "a/../../evil", "/etc/evil"} {
+		c.Logf("name %q", name)
+		archive := makeCharmZip(c, zipEntry{name: name, mode: 0644})
+		root := c.MkDir()
+		err := archive.ExpandToWithOptions(filepath.Join(root, "charm"), charm.ExpandOptions{})
+		c.Assert(err, gc.FitsTypeOf, &charm.PathEscapeError{})
+		c.Assert(err, gc.ErrorMatches, `path ".*" escapes the target directory`)
+		_, err = os.Lstat(filepath.Join(root, "evil"))
+		c.Assert(os.IsNotExist(err), jc.IsTrue)
+	}
+}
+
+func (s *ExpandSuite) TestSymlinkEscape(c *gc.C) {
+	archive := makeCharmZip(c, zipEntry{name: "hooks/bad", mode: os.ModeSymlink | 0777, data: "../../target"})
+	err := archive.ExpandToWithOptions(c.MkDir(), charm.ExpandOptions{})
+	c.Assert(err, gc.ErrorMatches, `symlink "hooks/bad" links out of charm: "../../target"`)
+	c.Assert(err, gc.FitsTypeOf, &charm.SymlinkEscapeError{})
+	c.Assert(err.(*charm.SymlinkEscapeError).Target, gc.Equals, "../../target")
+
+	archive = makeCharmZip(c, zipEntry{name: "bad", mode: os.ModeSymlink | 0777, data: "/target"})
+	err = archive.ExpandToWithOptions(c.MkDir(), charm.ExpandOptions{})
+	c.Assert(err, gc.ErrorMatches, `symlink "bad" is absolute: "/target"`)
+	c.Assert(err, gc.FitsTypeOf, &charm.SymlinkEscapeError{})
+}
+
+func (s *ExpandSuite) TestWriteThroughSymlink(c *gc.C) {
+	// Each link is within the charm, but writing through
+	// them would lead outside it.
+	archive := makeCharmZip(c,
+		zipEntry{name: "self", mode: os.ModeSymlink | 0777, data: "."},
+		zipEntry{name: "up", mode: os.ModeSymlink | 0777, data: "self/.."},
+		zipEntry{name: "up/evil", mode: 0644, data: "evil"},
+	)
+	root := c.MkDir()
+	err := archive.ExpandToWithOptions(filepath.Join(root, "charm"), charm.ExpandOptions{})
+	c.Assert(err, gc.ErrorMatches, `path "up/evil" escapes the target directory`)
+	c.Assert(err, gc.FitsTypeOf, &charm.PathEscapeError{})
+	_, err = os.Lstat(filepath.Join(root, "evil"))
+	c.Assert(os.IsNotExist(err), jc.IsTrue)
+}
+
+func (s *ExpandSuite) TestOverwritesExisting(c *gc.C) {
+	archive := makeCharmZip(c, zipEntry{name: "a/b", mode: 0644, data: "new"})
+	dir := c.MkDir()
+	err := os.MkdirAll(filepath.Join(dir, "a", "b", "c"), 0755)
+	c.Assert(err, jc.ErrorIsNil)
+	err = archive.ExpandToWithOptions(dir, charm.ExpandOptions{})
+	c.Assert(err, jc.ErrorIsNil)
+	data, err := ioutil.ReadFile(filepath.Join(dir, "a", "b"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(string(data), gc.Equals, "new")
+}
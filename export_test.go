@@ -3,6 +3,10 @@
 
 package charm
 
+import (
+	"strings"
+)
+
 // Export meaningful bits for tests only.
 
 var (
@@ -20,6 +24,21 @@ func MissingSeriesError() error {
 	return missingSeriesError
 }
 
+// IgnoreMatch reports whether the given path is
+// excluded by the given .charmignore content.
+func IgnoreMatch(patterns, path string, isDir bool) (bool, error) {
+	m, err := parseIgnore(strings.NewReader(patterns))
+	if err != nil {
+		return false, err
+	}
+	return m.match(path, isDir), nil
+}
+
 func (bd *BundleData) ClearUnmarshaledWithServices() {
 	bd.unmarshaledWithServices = false
 }
+
+const (
+	DurationPattern = durationPattern
+	BytesPattern    = bytesPattern
+)
@@ -0,0 +1,222 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package charm
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+)
+
+// ManifestEntry describes a single entry of a charm archive.
+type ManifestEntry struct {
+	// Path holds the slash-separated path of the entry
+	// relative to the charm root.
+	Path string `json:"path" yaml:"path"`
+
+	// Size holds the size of a file in bytes, or the
+	// length of the target of a symbolic link.
+	Size int64 `json:"size" yaml:"size"`
+
+	// Mode holds the type and permissions of the entry.
+	Mode os.FileMode `json:"mode" yaml:"mode"`
+
+	// SHA256 holds the hex-encoded SHA-256 hash of the
+	// content of a file. It is empty for other entries.
+	SHA256 string `json:"sha256,omitempty" yaml:"sha256,omitempty"`
+
+	// LinkTarget holds the target of a symbolic link.
+	LinkTarget string `json:"link-target,omitempty" yaml:"link-target,omitempty"`
+}
+
+// FileManifest describes every entry of a charm archive. See
+// CharmDir.FileManifest and CharmArchive.FileManifest.
+type FileManifest struct {
+	// Entries holds the entries, sorted by path.
+	Entries []ManifestEntry `json:"entries" yaml:"entries"`
+}
+
+// Entry returns the entry with the given path,
+// and reports whether it was found.
+func (m *FileManifest) Entry(path string) (ManifestEntry, bool) {
+	i := sort.Search(len(m.Entries), func(i int) bool {
+		return m.Entries[i].Path >= path
+	})
+	if i < len(m.Entries) && m.Entries[i].Path == path {
+		return m.Entries[i], true
+	}
+	return ManifestEntry{}, false
+}
+
+// ContentDigest returns the hex-encoded SHA-256 digest of the
+// files and symbolic links described by the manifest. See
+// CharmDir.ContentDigest for details.
+func (m *FileManifest) ContentDigest() string {
+	h := sha256.New()
+	for _, e := range m.Entries {
+		kind := "file"
+		sum := e.SHA256
+		switch {
+		case e.Mode.IsDir():
+			continue
+		case e.Mode&os.ModeSymlink != 0:
+			kind = "symlink"
+			sum = fmt.Sprintf("%x", sha256.Sum256([]byte(e.LinkTarget)))
+		case e.Mode&0111 != 0:
+			kind = "exec"
+		}
+		fmt.Fprintf(h, "%s\x00%s\x00%s\n", e.Path, kind, sum)
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// ManifestChange describes how an entry differs between two manifests.
+type ManifestChange struct {
+	Kind ChangeKind
+	Path string
+
+	// Old and New hold the entry in the old and new manifests.
+	// Old is nil for added entries and New is nil for removed ones.
+	Old, New *ManifestEntry
+}
+
+// DiffManifests returns the entries that differ between the old
+// and new manifests, sorted by path.
+func DiffManifests(old, new *FileManifest) []ManifestChange {
+	var changes []ManifestChange
+	i, j := 0, 0
+	for i < len(old.Entries) || j < len(new.Entries) {
+		switch {
+		case j == len(new.Entries) || i < len(old.Entries) && old.Entries[i].Path < new.Entries[j].Path:
+			changes = append(changes, ManifestChange{
+				Kind: ChangeRemoved,
+				Path: old.Entries[i].Path,
+				Old:  &old.Entries[i],
+			})
+			i++
+		case i == len(old.Entries) || new.Entries[j].Path < old.Entries[i].Path:
+			changes = append(changes, ManifestChange{
+				Kind: ChangeAdded,
+				Path: new.Entries[j].Path,
+				New:  &new.Entries[j],
+			})
+			j++
+		default:
+			if old.Entries[i] != new.Entries[j] {
+				changes = append(changes, ManifestChange{
+					Kind: ChangeModified,
+					Path: old.Entries[i].Path,
+					Old:  &old.Entries[i],
+					New:  &new.Entries[j],
+				})
+			}
+			i++
+			j++
+		}
+	}
+	return changes
+}
+
+// FileManifest returns a manifest of the archive that ArchiveTo
+// would write. It is equal to the FileManifest of the CharmArchive
+// read from that archive.
+func (dir *CharmDir) FileManifest() (*FileManifest, error) {
+	entries, err := dir.archiveEntries()
+	if err != nil {
+		return nil, err
+	}
+	var b manifestBuilder
+	for i := range entries {
+		e := &entries[i]
+		r, err := e.open()
+		if err != nil {
+			return nil, err
+		}
+		err = b.add(e.name, e.mode, r)
+		r.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return b.manifest(), nil
+}
+
+// FileManifest returns a manifest of the archive. The signature
+// entry added by SignCharmArchive is not included.
+func (a *CharmArchive) FileManifest() (*FileManifest, error) {
+	zipr, err := a.zopen.openZip()
+	if err != nil {
+		return nil, err
+	}
+	defer zipr.Close()
+	var b manifestBuilder
+	for _, f := range zipr.File {
+		if f.Name == SignatureFileName {
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		err = b.add(f.Name, f.Mode(), r)
+		r.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return b.manifest(), nil
+}
+
+// manifestBuilder accumulates the entries of a manifest.
+type manifestBuilder struct {
+	entries []ManifestEntry
+}
+
+// add adds the archive entry with the given name and mode, and
+// with content read from r, to the manifest. The root directory
+// is not included.
+func (b *manifestBuilder) add(name string, mode os.FileMode, r io.Reader) error {
+	name = path.Clean(name)
+	if name == "." {
+		return nil
+	}
+	e := ManifestEntry{
+		Path: name,
+		Mode: mode,
+	}
+	switch {
+	case mode.IsDir():
+	case mode&os.ModeSymlink != 0:
+		var buf [4096]byte
+		n, err := io.ReadFull(r, buf[:])
+		if err != io.ErrUnexpectedEOF && err != io.EOF {
+			if err == nil {
+				err = fmt.Errorf("symlink %q has a target that is too long", name)
+			}
+			return err
+		}
+		e.LinkTarget = string(buf[:n])
+		e.Size = int64(n)
+	default:
+		h := sha256.New()
+		n, err := io.Copy(h, r)
+		if err != nil {
+			return err
+		}
+		e.Size = n
+		e.SHA256 = fmt.Sprintf("%x", h.Sum(nil))
+	}
+	b.entries = append(b.entries, e)
+	return nil
+}
+
+func (b *manifestBuilder) manifest() *FileManifest {
+	sort.Slice(b.entries, func(i, j int) bool {
+		return b.entries[i].Path < b.entries[j].Path
+	})
+	return &FileManifest{Entries: b.entries}
+}
@@ -0,0 +1,119 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package charm_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"gopkg.in/juju/charm.v6"
+)
+
+type FileManifestSuite struct{}
+
+var _ = gc.Suite(&FileManifestSuite{})
+
+func (s *FileManifestSuite) TestDirMatchesArchive(c *gc.C) {
+	path := cloneDir(c, charmDirPath(c, "dummy"))
+	err := os.Symlink("install", filepath.Join(path, "hooks", "start"))
+	c.Assert(err, jc.ErrorIsNil)
+	dir, err := charm.ReadCharmDir(path)
+	c.Assert(err, jc.ErrorIsNil)
+	dirManifest, err := dir.FileManifest()
+	c.Assert(err, jc.ErrorIsNil)
+
+	var paths []string
+	for _, e := range dirManifest.Entries {
+		paths = append(paths, e.Path)
+	}
+	c.Assert(paths, jc.DeepEquals, []string{
+		"actions.yaml",
+		"config.yaml",
+		"empty",
+		"empty/.gitkeep",
+		"hooks",
+		"hooks/install",
+		"hooks/start",
+		"metadata.yaml",
+		"revision",
+		"src",
+		"src/hello.c",
+	})
+	install, ok := dirManifest.Entry("hooks/install")
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(install, jc.DeepEquals, charm.ManifestEntry{
+		Path:   "hooks/install",
+		Size:   25,
+		Mode:   0755,
+		SHA256: "1fad43abc1e7f67d37fa25054683c9e6c7ddb1816f4b888b6cb77aa721c34865",
+	})
+	start, ok := dirManifest.Entry("hooks/start")
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(start, jc.DeepEquals, charm.ManifestEntry{
+		Path:       "hooks/start",
+		Size:       7,
+		Mode:       os.ModeSymlink | 0777,
+		LinkTarget: "install",
+	})
+	hooks, ok := dirManifest.Entry("hooks")
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(hooks.Mode.IsDir(), jc.IsTrue)
+	_, ok = dirManifest.Entry("nothing")
+	c.Assert(ok, jc.IsFalse)
+
+	var buf bytes.Buffer
+	err = dir.ArchiveTo(&buf)
+	c.Assert(err, jc.ErrorIsNil)
+	archive, err := charm.ReadCharmArchiveBytes(buf.Bytes())
+	c.Assert(err, jc.ErrorIsNil)
+	archiveManifest, err := archive.FileManifest()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(archiveManifest, jc.DeepEquals, dirManifest)
+	c.Assert(charm.DiffManifests(dirManifest, archiveManifest), gc.HasLen, 0)
+
+	digest, err := archive.ContentDigest()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(digest, gc.Equals, archiveManifest.ContentDigest())
+}
+
+func (s *FileManifestSuite) TestDiffManifests(c *gc.C) {
+	path := cloneDir(c, charmDirPath(c, "dummy"))
+	readManifest := func() *charm.FileManifest {
+		dir, err := charm.ReadCharmDir(path)
+		c.Assert(err, jc.ErrorIsNil)
+		m, err := dir.FileManifest()
+		c.Assert(err, jc.ErrorIsNil)
+		return m
+	}
+	old := readManifest()
+
+	err := ioutil.WriteFile(filepath.Join(path, "src", "hello.c"), []byte("changed"), 0644)
+	c.Assert(err, jc.ErrorIsNil)
+	err = os.Remove(filepath.Join(path, "actions.yaml"))
+	c.Assert(err, jc.ErrorIsNil)
+	err = ioutil.WriteFile(filepath.Join(path, "README.md"), []byte("hello"), 0644)
+	c.Assert(err, jc.ErrorIsNil)
+	new := readManifest()
+
+	changes := charm.DiffManifests(old, new)
+	c.Assert(changes, gc.HasLen, 3)
+	c.Assert(changes[0].Kind, gc.Equals, charm.ChangeAdded)
+	c.Assert(changes[0].Path, gc.Equals, "README.md")
+	c.Assert(changes[0].Old, gc.IsNil)
+	c.Assert(changes[0].New.Size, gc.Equals, int64(5))
+
+	c.Assert(changes[1].Kind, gc.Equals, charm.ChangeRemoved)
+	c.Assert(changes[1].Path, gc.Equals, "actions.yaml")
+	c.Assert(changes[1].New, gc.IsNil)
+
+	c.Assert(changes[2].Kind, gc.Equals, charm.ChangeModified)
+	c.Assert(changes[2].Path, gc.Equals, "src/hello.c")
+	c.Assert(changes[2].Old.SHA256, gc.Not(gc.Equals), changes[2].New.SHA256)
+	c.Assert(changes[2].New.Size, gc.Equals, int64(7))
+}
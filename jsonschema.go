@@ -0,0 +1,248 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package charm
+
+import (
+	"time"
+)
+
+// JSONSchemaVersion holds the JSON Schema version of the
+// documents returned by Config.JSONSchema and CharmJSONSchema.
+const JSONSchemaVersion = "http://json-schema.org/draft-04/schema#"
+
+// durationPattern matches the durations accepted by time.ParseDuration.
+const durationPattern = `^[-+]?(0|(([0-9]+(\.[0-9]*)?|\.[0-9]+)(ns|us|µs|ms|s|m|h))+)$`
+
+// bytesPattern matches the sizes accepted by bytes options.
+const bytesPattern = `^\s*[0-9]*\.?[0-9]+\s*([KMGTPkmgtp]([Ii]?[Bb])?|[Bb])?\s*$`
+
+// JSONSchema returns a JSON Schema (draft 4) document describing the
+// settings accepted by ValidateSettings. Each option is described by
+// a property holding its type, description, default and constraints.
+// Secret options are strings with the "password" format, and their
+// defaults are omitted.
+func (c *Config) JSONSchema() map[string]interface{} {
+	schema := c.schema(false)
+	schema["$schema"] = JSONSchemaVersion
+	return schema
+}
+
+// schema returns the schema of the config. OpenAPI schemas do not
+// support dependencies, so the requires and conflicts-with constraints
+// are only included when openAPI is false.
+func (c *Config) schema(openAPI bool) map[string]interface{} {
+	properties := make(map[string]interface{})
+	dependencies := make(map[string]interface{})
+	if c != nil {
+		for name, option := range c.Options {
+			properties[name] = option.schema()
+			if dependency := option.dependencySchema(); dependency != nil && !openAPI {
+				dependencies[name] = dependency
+			}
+		}
+	}
+	schema := map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(dependencies) > 0 {
+		schema["dependencies"] = dependencies
+	}
+	return schema
+}
+
+// schema returns the schema of the values of the option.
+func (option Option) schema() map[string]interface{} {
+	schema := make(map[string]interface{})
+	switch option.Type {
+	case "", "string", "secret":
+		schema["type"] = "string"
+		if option.Type == "secret" {
+			schema["format"] = "password"
+		}
+		if option.Pattern != "" {
+			schema["pattern"] = "^(?:" + option.Pattern + ")$"
+		}
+		setLimit(schema, "minLength", option.MinLength)
+		setLimit(schema, "maxLength", option.MaxLength)
+	case "int", "float":
+		schema["type"] = "integer"
+		if option.Type == "float" {
+			schema["type"] = "number"
+		}
+		setBound(schema, "minimum", option.Minimum)
+		setBound(schema, "maximum", option.Maximum)
+	case "boolean":
+		schema["type"] = "boolean"
+	case "list":
+		schema["type"] = "array"
+		schema["items"] = Option{Type: option.itemType()}.schema()
+		setLimit(schema, "minItems", option.MinLength)
+		setLimit(schema, "maxItems", option.MaxLength)
+	case "map":
+		schema["type"] = "object"
+		schema["additionalProperties"] = Option{Type: option.itemType()}.schema()
+		setLimit(schema, "minProperties", option.MinLength)
+		setLimit(schema, "maxProperties", option.MaxLength)
+	case "enum":
+		allowed := make([]interface{}, len(option.Allowed))
+		for i, value := range option.Allowed {
+			allowed[i] = value
+		}
+		schema["type"] = "string"
+		schema["enum"] = allowed
+	case "duration":
+		schema["type"] = "string"
+		schema["pattern"] = durationPattern
+	case "bytes":
+		// Sizes may be given as a number of bytes or as a string
+		// with a unit suffix. Bounds can only be checked for the
+		// former.
+		number := map[string]interface{}{
+			"type":    "integer",
+			"minimum": 0,
+		}
+		setBound(number, "minimum", option.Minimum)
+		setBound(number, "maximum", option.Maximum)
+		schema["oneOf"] = []interface{}{
+			number,
+			map[string]interface{}{
+				"type":    "string",
+				"pattern": bytesPattern,
+			},
+		}
+	}
+	if option.Description != "" {
+		schema["description"] = option.Description
+	}
+	// The defaults of secret options are not published,
+	// as they would otherwise be shown in plain text.
+	if option.Default != nil && option.Type != "secret" {
+		def := option.Default
+		if d, ok := def.(time.Duration); ok {
+			def = d.String()
+		}
+		schema["default"] = def
+	}
+	return schema
+}
+
+// dependencySchema returns the schema that the settings must
+// satisfy when the option is set, or nil if there is none.
+func (option Option) dependencySchema() map[string]interface{} {
+	if len(option.Requires) == 0 && len(option.ConflictsWith) == 0 {
+		return nil
+	}
+	schema := make(map[string]interface{})
+	if len(option.Requires) > 0 {
+		required := make([]interface{}, len(option.Requires))
+		for i, name := range option.Requires {
+			required[i] = name
+		}
+		schema["required"] = required
+	}
+	if len(option.ConflictsWith) > 0 {
+		conflicts := make([]interface{}, len(option.ConflictsWith))
+		for i, name := range option.ConflictsWith {
+			conflicts[i] = map[string]interface{}{
+				"required": []interface{}{name},
+			}
+		}
+		schema["not"] = map[string]interface{}{
+			"anyOf": conflicts,
+		}
+	}
+	return schema
+}
+
+func setLimit(schema map[string]interface{}, key string, limit *int) {
+	if limit != nil {
+		schema[key] = *limit
+	}
+}
+
+func setBound(schema map[string]interface{}, key string, bound *float64) {
+	if bound != nil {
+		schema[key] = *bound
+	}
+}
+
+// CharmJSONSchema returns a JSON Schema (draft 4) document describing
+// the config and actions of the given charm. The document's "config"
+// property holds the schema returned by Config.JSONSchema, and its
+// "actions" property holds the params schema of each action.
+func CharmJSONSchema(ch Charm) map[string]interface{} {
+	actions := make(map[string]interface{})
+	for name, spec := range charmActions(ch) {
+		actions[name] = actionSchema(spec)
+	}
+	schema := map[string]interface{}{
+		"$schema": JSONSchemaVersion,
+		"type":    "object",
+		"properties": map[string]interface{}{
+			"config": ch.Config().schema(false),
+			"actions": map[string]interface{}{
+				"type":                 "object",
+				"properties":           actions,
+				"additionalProperties": false,
+			},
+		},
+	}
+	if meta := ch.Meta(); meta != nil {
+		schema["title"] = meta.Name
+		if meta.Summary != "" {
+			schema["description"] = meta.Summary
+		}
+	}
+	return schema
+}
+
+// CharmOpenAPIComponents returns an OpenAPI 3 document holding a
+// components object that describes the config and actions of the given
+// charm. The config schema is named "<charm>.config" and the params
+// schema of each action is named "<charm>.actions.<action>". The
+// requires and conflicts-with constraints of config options cannot be
+// expressed in OpenAPI and are not included.
+func CharmOpenAPIComponents(ch Charm) map[string]interface{} {
+	prefix := "charm"
+	if meta := ch.Meta(); meta != nil && meta.Name != "" {
+		prefix = meta.Name
+	}
+	schemas := map[string]interface{}{
+		prefix + ".config": ch.Config().schema(true),
+	}
+	for name, spec := range charmActions(ch) {
+		schemas[prefix+".actions."+name] = actionSchema(spec)
+	}
+	return map[string]interface{}{
+		"components": map[string]interface{}{
+			"schemas": schemas,
+		},
+	}
+}
+
+// charmActions returns the action specs of the given charm.
+func charmActions(ch Charm) map[string]ActionSpec {
+	if actions := ch.Actions(); actions != nil {
+		return actions.ActionSpecs
+	}
+	return nil
+}
+
+// actionSchema returns the schema of the params of the given
+// action, with its description.
+func actionSchema(spec ActionSpec) map[string]interface{} {
+	schema := make(map[string]interface{})
+	for key, value := range spec.Params {
+		schema[key] = value
+	}
+	if _, ok := schema["type"]; !ok {
+		schema["type"] = "object"
+	}
+	if spec.Description != "" {
+		schema["description"] = spec.Description
+	}
+	return schema
+}
@@ -0,0 +1,187 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package charm_test
+
+import (
+	"encoding/json"
+
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"gopkg.in/juju/charm.v6"
+)
+
+type JSONSchemaSuite struct{}
+
+var _ = gc.Suite(&JSONSchemaSuite{})
+
+const schemaConfig = `
+options:
+    name:
+        type: string
+        description: The name.
+        pattern: '[a-z]+'
+        max-length: 10
+    password:
+        type: secret
+        default: hunter2
+        requires: [name]
+        conflicts-with: [anonymous]
+    anonymous:
+        type: boolean
+        default: false
+    port:
+        type: int
+        minimum: 1
+        default: 80
+    hosts:
+        type: list
+        items: string
+        min-length: 1
+    level:
+        type: enum
+        allowed: [debug, info]
+    timeout:
+        type: duration
+        default: 30s
+`
+
+const schemaActions = `
+backup:
+    description: Take a backup.
+    params:
+        target:
+            type: string
+    required: [target]
+`
+
+func (s *JSONSchemaSuite) TestConfigJSONSchema(c *gc.C) {
+	ch := newYAMLCharm(c, "name: app\nsummary: An app.\ndescription: d\n", schemaConfig, "")
+	c.Assert(ch.Config().JSONSchema(), jc.DeepEquals, map[string]interface{}{
+		"$schema":              charm.JSONSchemaVersion,
+		"type":                 "object",
+		"additionalProperties": false,
+		"properties": map[string]interface{}{
+			"name": map[string]interface{}{
+				"type":        "string",
+				"description": "The name.",
+				"pattern":     "^(?:[a-z]+)$",
+				"maxLength":   10,
+			},
+			"password": map[string]interface{}{
+				"type":   "string",
+				"format": "password",
+			},
+			"anonymous": map[string]interface{}{
+				"type":    "boolean",
+				"default": false,
+			},
+			"port": map[string]interface{}{
+				"type":    "integer",
+				"minimum": 1.0,
+				"default": int64(80),
+			},
+			"hosts": map[string]interface{}{
+				"type":     "array",
+				"items":    map[string]interface{}{"type": "string"},
+				"minItems": 1,
+			},
+			"level": map[string]interface{}{
+				"type": "string",
+				"enum": []interface{}{"debug", "info"},
+			},
+			"timeout": map[string]interface{}{
+				"type":    "string",
+				"pattern": charm.DurationPattern,
+				"default": "30s",
+			},
+		},
+		"dependencies": map[string]interface{}{
+			"password": map[string]interface{}{
+				"required": []interface{}{"name"},
+				"not": map[string]interface{}{
+					"anyOf": []interface{}{
+						map[string]interface{}{"required": []interface{}{"anonymous"}},
+					},
+				},
+			},
+		},
+	})
+}
+
+func (s *JSONSchemaSuite) TestDurationAndBytesPatterns(c *gc.C) {
+	for _, d := range []string{"0", "1s", "1.5h", "-2m30s", "100ms", "3µs"} {
+		c.Check(d, gc.Matches, charm.DurationPattern)
+	}
+	for _, d := range []string{"", "1", "s", "1d"} {
+		c.Check(d, gc.Not(gc.Matches), charm.DurationPattern)
+	}
+	for _, b := range []string{"1", "1.5G", "2KiB", "3mb", "4 B"} {
+		c.Check(b, gc.Matches, charm.BytesPattern)
+	}
+	for _, b := range []string{"", "-1", "1X", "K"} {
+		c.Check(b, gc.Not(gc.Matches), charm.BytesPattern)
+	}
+}
+
+func (s *JSONSchemaSuite) TestCharmJSONSchema(c *gc.C) {
+	ch := newYAMLCharm(c, "name: app\nsummary: An app.\ndescription: d\n", schemaConfig, schemaActions)
+	schema := charm.CharmJSONSchema(ch)
+	c.Assert(schema["$schema"], gc.Equals, charm.JSONSchemaVersion)
+	c.Assert(schema["title"], gc.Equals, "app")
+	c.Assert(schema["description"], gc.Equals, "An app.")
+
+	properties := schema["properties"].(map[string]interface{})
+	config := ch.Config().JSONSchema()
+	delete(config, "$schema")
+	c.Assert(properties["config"], jc.DeepEquals, config)
+
+	actions := properties["actions"].(map[string]interface{})["properties"].(map[string]interface{})
+	c.Assert(actions, jc.DeepEquals, map[string]interface{}{
+		"backup": map[string]interface{}{
+			"type":        "object",
+			"title":       "backup",
+			"description": "Take a backup.",
+			"properties": map[string]interface{}{
+				"target": map[string]interface{}{"type": "string"},
+			},
+			"required": []interface{}{"target"},
+		},
+	})
+
+	// The document can be serialised.
+	_, err := json.Marshal(schema)
+	c.Assert(err, gc.IsNil)
+}
+
+func (s *JSONSchemaSuite) TestCharmOpenAPIComponents(c *gc.C) {
+	ch := newYAMLCharm(c, "name: app\nsummary: An app.\ndescription: d\n", schemaConfig, schemaActions)
+	doc := charm.CharmOpenAPIComponents(ch)
+	schemas := doc["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	c.Assert(schemas, gc.HasLen, 2)
+
+	config := ch.Config().JSONSchema()
+	delete(config, "$schema")
+	delete(config, "dependencies")
+	c.Assert(schemas["app.config"], jc.DeepEquals, config)
+	c.Assert(schemas["app.actions.backup"], gc.NotNil)
+
+	_, err := json.Marshal(doc)
+	c.Assert(err, gc.IsNil)
+}
+
+func (s *JSONSchemaSuite) TestSecretDefaultsNotPublished(c *gc.C) {
+	ch := newYAMLCharm(c, "name: app\nsummary: An app.\ndescription: d\n", schemaConfig, schemaActions)
+	c.Assert(ch.Config().Options["password"].Default, gc.Equals, "hunter2")
+	for i, doc := range []map[string]interface{}{
+		ch.Config().JSONSchema(),
+		charm.CharmJSONSchema(ch),
+		charm.CharmOpenAPIComponents(ch),
+	} {
+		c.Logf("document %d", i)
+		data, err := json.Marshal(doc)
+		c.Assert(err, gc.IsNil)
+		c.Assert(string(data), gc.Not(jc.Contains), "hunter2")
+	}
+}
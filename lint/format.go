@@ -0,0 +1,130 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package lint
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+
+	"gopkg.in/juju/charm.v6"
+)
+
+// WriteText writes a human readable form of the report to w,
+// one violation per line.
+func WriteText(w io.Writer, r *Report) error {
+	errorCount := 0
+	for _, v := range r.Violations {
+		location := r.Path
+		if v.Path != "" {
+			location += "/" + v.Path
+		}
+		if _, err := fmt.Fprintf(w, "%s: %s: %s (%s)\n", location, v.Severity, v.Message, v.Code); err != nil {
+			return err
+		}
+		if v.Severity == charm.SeverityError {
+			errorCount++
+		}
+	}
+	_, err := fmt.Fprintf(w, "%d problem(s) found, %d error(s)\n", len(r.Violations), errorCount)
+	return err
+}
+
+type jsonViolation struct {
+	Code     string `json:"code"`
+	Path     string `json:"path,omitempty"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+type jsonReport struct {
+	Charm      string          `json:"charm"`
+	Path       string          `json:"path"`
+	Rules      []string        `json:"rules"`
+	Violations []jsonViolation `json:"violations"`
+}
+
+// WriteJSON writes the report to w as a JSON object.
+func WriteJSON(w io.Writer, r *Report) error {
+	out := jsonReport{
+		Charm:      r.Charm,
+		Path:       r.Path,
+		Rules:      r.Rules,
+		Violations: make([]jsonViolation, 0, len(r.Violations)),
+	}
+	for _, v := range r.Violations {
+		out.Violations = append(out.Violations, jsonViolation{
+			Code:     v.Code,
+			Path:     v.Path,
+			Severity: string(v.Severity),
+			Message:  v.Message,
+		})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+type junitSuite struct {
+	XMLName  xml.Name    `xml:"testsuite"`
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string         `xml:"name,attr"`
+	ClassName string         `xml:"classname,attr"`
+	Failures  []junitFailure `xml:"failure"`
+}
+
+type junitFailure struct {
+	Type    string `xml:"type,attr"`
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the report to w as a JUnit XML test suite,
+// with a test case for each rule that was run. Every violation
+// is recorded as a failure of its rule's test case.
+func WriteJUnit(w io.Writer, r *Report) error {
+	suite := junitSuite{
+		Name:  r.Charm,
+		Tests: len(r.Rules),
+	}
+	cases := make(map[string]int)
+	for _, name := range r.Rules {
+		cases[name] = len(suite.Cases)
+		suite.Cases = append(suite.Cases, junitCase{
+			Name:      name,
+			ClassName: "charm-lint." + r.Charm,
+		})
+	}
+	for _, v := range r.Violations {
+		i, ok := cases[v.Code]
+		if !ok {
+			return fmt.Errorf("violation %q does not belong to a rule that was run", v.Code)
+		}
+		if len(suite.Cases[i].Failures) == 0 {
+			suite.Failures++
+		}
+		suite.Cases[i].Failures = append(suite.Cases[i].Failures, junitFailure{
+			Type:    string(v.Severity),
+			Message: v.Message,
+			Text:    v.Path,
+		})
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suite); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,103 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package lint_test
+
+import (
+	"bytes"
+	"encoding/json"
+
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"gopkg.in/juju/charm.v6"
+	"gopkg.in/juju/charm.v6/lint"
+)
+
+type FormatSuite struct{}
+
+var _ = gc.Suite(&FormatSuite{})
+
+var formatReport = &lint.Report{
+	Charm: "lintme",
+	Path:  "/charms/lintme",
+	Rules: []string{"hook-not-executable", "icon", "readme"},
+	Violations: []charm.Violation{{
+		Code:     "hook-not-executable",
+		Path:     "hooks/start",
+		Severity: charm.SeverityError,
+		Message:  `hook "start" is not executable`,
+	}, {
+		Code:     "icon",
+		Severity: charm.SeverityWarning,
+		Message:  "charm has no icon.svg file",
+	}},
+}
+
+func (s *FormatSuite) TestWriteText(c *gc.C) {
+	var buf bytes.Buffer
+	err := lint.WriteText(&buf, formatReport)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(buf.String(), gc.Equals, `
+/charms/lintme/hooks/start: error: hook "start" is not executable (hook-not-executable)
+/charms/lintme: warning: charm has no icon.svg file (icon)
+2 problem(s) found, 1 error(s)
+`[1:])
+}
+
+func (s *FormatSuite) TestWriteJSON(c *gc.C) {
+	var buf bytes.Buffer
+	err := lint.WriteJSON(&buf, formatReport)
+	c.Assert(err, jc.ErrorIsNil)
+	var got interface{}
+	err = json.Unmarshal(buf.Bytes(), &got)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(got, jc.DeepEquals, map[string]interface{}{
+		"charm": "lintme",
+		"path":  "/charms/lintme",
+		"rules": []interface{}{"hook-not-executable", "icon", "readme"},
+		"violations": []interface{}{
+			map[string]interface{}{
+				"code":     "hook-not-executable",
+				"path":     "hooks/start",
+				"severity": "error",
+				"message":  `hook "start" is not executable`,
+			},
+			map[string]interface{}{
+				"code":     "icon",
+				"severity": "warning",
+				"message":  "charm has no icon.svg file",
+			},
+		},
+	})
+}
+
+func (s *FormatSuite) TestWriteJUnit(c *gc.C) {
+	var buf bytes.Buffer
+	err := lint.WriteJUnit(&buf, formatReport)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(buf.String(), gc.Equals, `
+<?xml version="1.0" encoding="UTF-8"?>
+<testsuite name="lintme" tests="3" failures="2">
+  <testcase name="hook-not-executable" classname="charm-lint.lintme">
+    <failure type="error" message="hook &#34;start&#34; is not executable">hooks/start</failure>
+  </testcase>
+  <testcase name="icon" classname="charm-lint.lintme">
+    <failure type="warning" message="charm has no icon.svg file"></failure>
+  </testcase>
+  <testcase name="readme" classname="charm-lint.lintme"></testcase>
+</testsuite>
+`[1:])
+}
+
+func (s *FormatSuite) TestWriteJUnitUnknownRule(c *gc.C) {
+	report := &lint.Report{
+		Charm: "lintme",
+		Violations: []charm.Violation{{
+			Code: "other",
+		}},
+	}
+	var buf bytes.Buffer
+	err := lint.WriteJUnit(&buf, report)
+	c.Assert(err, gc.ErrorMatches, `violation "other" does not belong to a rule that was run`)
+}
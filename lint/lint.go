@@ -0,0 +1,171 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+// Package lint checks a charm directory for common mistakes that
+// are not detected when the charm metadata is parsed.
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/juju/errors"
+
+	"gopkg.in/juju/charm.v6"
+)
+
+// Rule defines a single check made by the linter.
+type Rule struct {
+	// Name uniquely identifies the rule. It is used as the
+	// code of every violation reported by the rule.
+	Name string
+
+	// Description holds a short description of what the
+	// rule checks.
+	Description string
+
+	// Severity holds the severity of the violations reported
+	// by the rule.
+	Severity charm.Severity
+
+	// Check runs the rule against the charm held in ctx,
+	// reporting any problems with ctx.Reportf. A returned
+	// error indicates that the check could not be made.
+	Check func(ctx *Context) error
+}
+
+// Context holds the charm being linted and collects the
+// violations reported by the rule currently being run.
+type Context struct {
+	// Path holds the path to the charm directory.
+	Path string
+
+	// Charm holds the charm read from Path.
+	Charm *charm.CharmDir
+
+	rule       *Rule
+	violations []charm.Violation
+}
+
+// Reportf reports a violation of the current rule. The path
+// should be relative to the charm directory, or empty if the
+// violation does not relate to a specific file.
+func (ctx *Context) Reportf(path string, f string, a ...interface{}) {
+	ctx.violations = append(ctx.violations, charm.Violation{
+		Code:     ctx.rule.Name,
+		Path:     path,
+		Severity: ctx.rule.Severity,
+		Message:  fmt.Sprintf(f, a...),
+	})
+}
+
+var (
+	registryMu sync.Mutex
+	registry   = make(map[string]Rule)
+)
+
+// Register makes a rule available to the linter. It panics if
+// the rule has no name or check, or if a rule with the same name
+// has already been registered.
+func Register(rule Rule) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if rule.Name == "" || rule.Check == nil {
+		panic("lint: rule must have a name and a check")
+	}
+	if _, ok := registry[rule.Name]; ok {
+		panic(fmt.Sprintf("lint: rule %q registered twice", rule.Name))
+	}
+	if rule.Severity == "" {
+		rule.Severity = charm.SeverityError
+	}
+	registry[rule.Name] = rule
+}
+
+// Rules returns all the registered rules, sorted by name.
+func Rules() []Rule {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	rules := make([]Rule, 0, len(registry))
+	for _, rule := range registry {
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].Name < rules[j].Name
+	})
+	return rules
+}
+
+// LookupRules returns the registered rules with the given names,
+// in the order given.
+func LookupRules(names ...string) ([]Rule, error) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	rules := make([]Rule, 0, len(names))
+	for _, name := range names {
+		rule, ok := registry[name]
+		if !ok {
+			return nil, errors.NotFoundf("lint rule %q", name)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// Report holds the result of linting a charm.
+type Report struct {
+	// Charm holds the name of the linted charm.
+	Charm string
+
+	// Path holds the path of the linted charm directory.
+	Path string
+
+	// Rules holds the names of the rules that were run.
+	Rules []string
+
+	// Violations holds all the problems found, ordered by
+	// rule and then by the order in which they were reported.
+	Violations []charm.Violation
+}
+
+// HasErrors reports whether any of the violations in the
+// report has error severity.
+func (r *Report) HasErrors() bool {
+	for _, v := range r.Violations {
+		if v.Severity == charm.SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Lint reads the charm directory at path and runs the given rules
+// against it. If no rules are given, all registered rules are run.
+func Lint(path string, rules ...Rule) (*Report, error) {
+	ch, err := charm.ReadCharmDir(path)
+	if err != nil {
+		return nil, errors.Annotatef(err, "cannot read charm %q", path)
+	}
+	if len(rules) == 0 {
+		rules = Rules()
+	}
+	report := &Report{
+		Charm: ch.Meta().Name,
+		Path:  path,
+	}
+	for i := range rules {
+		rule := rules[i]
+		ctx := &Context{
+			Path:  path,
+			Charm: ch,
+			rule:  &rule,
+		}
+		if err := rule.Check(ctx); err != nil {
+			return nil, errors.Annotatef(err, "lint rule %q failed", rule.Name)
+		}
+		report.Rules = append(report.Rules, rule.Name)
+		report.Violations = append(report.Violations, ctx.violations...)
+	}
+	return report, nil
+}
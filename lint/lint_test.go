@@ -0,0 +1,108 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package lint_test
+
+import (
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"gopkg.in/juju/charm.v6"
+	"gopkg.in/juju/charm.v6/lint"
+)
+
+type LintSuite struct{}
+
+var _ = gc.Suite(&LintSuite{})
+
+func (s *LintSuite) TestRulesAreSorted(c *gc.C) {
+	rules := lint.Rules()
+	c.Assert(len(rules) > 0, jc.IsTrue)
+	for i := 1; i < len(rules); i++ {
+		c.Assert(rules[i-1].Name < rules[i].Name, jc.IsTrue)
+	}
+}
+
+func (s *LintSuite) TestRegisterDuplicatePanics(c *gc.C) {
+	rule := lint.Rule{
+		Name:  "icon",
+		Check: func(*lint.Context) error { return nil },
+	}
+	c.Assert(func() { lint.Register(rule) }, gc.PanicMatches, `lint: rule "icon" registered twice`)
+}
+
+func (s *LintSuite) TestRegisterWithoutCheckPanics(c *gc.C) {
+	c.Assert(func() { lint.Register(lint.Rule{Name: "nothing"}) }, gc.PanicMatches, `lint: rule must have a name and a check`)
+}
+
+func (s *LintSuite) TestLookupRules(c *gc.C) {
+	rules, err := lint.LookupRules("readme", "icon")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(rules, gc.HasLen, 2)
+	c.Assert(rules[0].Name, gc.Equals, "readme")
+	c.Assert(rules[1].Name, gc.Equals, "icon")
+
+	_, err = lint.LookupRules("no-such-rule")
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *LintSuite) TestLintCustomRule(c *gc.C) {
+	path := makeCharm(c, map[string]charmFile{})
+	rule := lint.Rule{
+		Name:     "custom",
+		Severity: charm.SeverityWarning,
+		Check: func(ctx *lint.Context) error {
+			ctx.Reportf("metadata.yaml", "charm %q looks odd", ctx.Charm.Meta().Name)
+			return nil
+		},
+	}
+	report, err := lint.Lint(path, rule)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(report, jc.DeepEquals, &lint.Report{
+		Charm: "lintme",
+		Path:  path,
+		Rules: []string{"custom"},
+		Violations: []charm.Violation{{
+			Code:     "custom",
+			Path:     "metadata.yaml",
+			Severity: charm.SeverityWarning,
+			Message:  `charm "lintme" looks odd`,
+		}},
+	})
+	c.Assert(report.HasErrors(), jc.IsFalse)
+}
+
+func (s *LintSuite) TestLintRuleError(c *gc.C) {
+	path := makeCharm(c, map[string]charmFile{})
+	rule := lint.Rule{
+		Name: "broken",
+		Check: func(ctx *lint.Context) error {
+			return errors.New("boom")
+		},
+	}
+	_, err := lint.Lint(path, rule)
+	c.Assert(err, gc.ErrorMatches, `lint rule "broken" failed: boom`)
+}
+
+func (s *LintSuite) TestLintBadCharm(c *gc.C) {
+	_, err := lint.Lint(c.MkDir())
+	c.Assert(err, gc.ErrorMatches, `cannot read charm ".*": .*metadata.yaml.*`)
+}
+
+func (s *LintSuite) TestLintAllRules(c *gc.C) {
+	path := makeCharm(c, map[string]charmFile{
+		"README.md":                {content: "readme"},
+		"icon.svg":                 {content: "<svg/>"},
+		"hooks/install":            {content: "#!/bin/sh", mode: 0755},
+		"hooks/db-relation-joined": {content: "#!/bin/sh", mode: 0755},
+		"hooks/website-relation-changed": {
+			content: "#!/bin/sh",
+			mode:    0755,
+		},
+	})
+	report, err := lint.Lint(path)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(report.Violations, gc.HasLen, 0)
+	c.Assert(report.Rules, gc.HasLen, len(lint.Rules()))
+}
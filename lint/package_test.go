@@ -0,0 +1,64 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package lint_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gc "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) {
+	gc.TestingT(t)
+}
+
+// charmFile describes a file to be created by makeCharm.
+type charmFile struct {
+	content string
+	mode    os.FileMode
+	link    string
+}
+
+const minimalMeta = `
+name: lintme
+summary: A charm for linting.
+description: A charm for linting.
+provides:
+  website: http
+requires:
+  db: mysql
+`
+
+// makeCharm creates a charm directory holding the given files
+// and returns its path. A metadata.yaml file is created
+// unless one is given.
+func makeCharm(c *gc.C, files map[string]charmFile) string {
+	dir := filepath.Join(c.MkDir(), "lintme")
+	if _, ok := files["metadata.yaml"]; !ok {
+		files["metadata.yaml"] = charmFile{content: minimalMeta}
+	}
+	for name, f := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		c.Assert(err, gc.IsNil)
+		if f.link != "" {
+			err = os.Symlink(f.link, path)
+			c.Assert(err, gc.IsNil)
+			continue
+		}
+		mode := f.mode
+		if mode == 0 {
+			mode = 0644
+		}
+		err = ioutil.WriteFile(path, []byte(f.content), mode)
+		c.Assert(err, gc.IsNil)
+		// Make sure the umask does not interfere.
+		err = os.Chmod(path, mode)
+		c.Assert(err, gc.IsNil)
+	}
+	return dir
+}
@@ -0,0 +1,222 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package lint
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/juju/charm.v6"
+	"gopkg.in/juju/charm.v6/hooks"
+)
+
+func init() {
+	Register(Rule{
+		Name:        "unknown-hook",
+		Description: "files in the hooks directory that are not valid hook names",
+		Severity:    charm.SeverityWarning,
+		Check:       checkUnknownHooks,
+	})
+	Register(Rule{
+		Name:        "hook-not-executable",
+		Description: "hooks that are not executable",
+		Severity:    charm.SeverityError,
+		Check:       checkHooksExecutable,
+	})
+	Register(Rule{
+		Name:        "config-description",
+		Description: "config options without a description",
+		Severity:    charm.SeverityWarning,
+		Check:       checkConfigDescriptions,
+	})
+	Register(Rule{
+		Name:        "relation-hooks",
+		Description: "relations that have no hook scripts",
+		Severity:    charm.SeverityWarning,
+		Check:       checkRelationHooks,
+	})
+	Register(Rule{
+		Name:        "readme",
+		Description: "charms without a README file",
+		Severity:    charm.SeverityWarning,
+		Check:       checkReadme,
+	})
+	Register(Rule{
+		Name:        "icon",
+		Description: "charms without an icon.svg file",
+		Severity:    charm.SeverityWarning,
+		Check:       checkIcon,
+	})
+	Register(Rule{
+		Name:        "absolute-symlink",
+		Description: "symbolic links with absolute targets",
+		Severity:    charm.SeverityError,
+		Check:       checkAbsoluteSymlinks,
+	})
+}
+
+// hookFiles returns information on all the non-directory
+// entries in the charm's hooks directory, sorted by name.
+func hookFiles(ctx *Context) ([]os.FileInfo, error) {
+	infos, err := ioutil.ReadDir(filepath.Join(ctx.Path, "hooks"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	files := infos[:0]
+	for _, info := range infos {
+		if !info.IsDir() {
+			files = append(files, info)
+		}
+	}
+	return files, nil
+}
+
+func checkUnknownHooks(ctx *Context) error {
+	files, err := hookFiles(ctx)
+	if err != nil {
+		return err
+	}
+	known := ctx.Charm.Meta().Hooks()
+	for _, info := range files {
+		if !known[info.Name()] {
+			ctx.Reportf("hooks/"+info.Name(), "%q is not a valid hook name for this charm", info.Name())
+		}
+	}
+	return nil
+}
+
+func checkHooksExecutable(ctx *Context) error {
+	files, err := hookFiles(ctx)
+	if err != nil {
+		return err
+	}
+	known := ctx.Charm.Meta().Hooks()
+	for _, info := range files {
+		if !known[info.Name()] {
+			continue
+		}
+		path := "hooks/" + info.Name()
+		mode := info.Mode()
+		if mode&os.ModeSymlink != 0 {
+			// Check the mode of the file linked to.
+			target, err := os.Stat(filepath.Join(ctx.Path, "hooks", info.Name()))
+			if err != nil {
+				ctx.Reportf(path, "cannot read hook %q: %v", info.Name(), err)
+				continue
+			}
+			mode = target.Mode()
+		}
+		if mode&0100 == 0 {
+			ctx.Reportf(path, "hook %q is not executable", info.Name())
+		}
+	}
+	return nil
+}
+
+func checkConfigDescriptions(ctx *Context) error {
+	options := ctx.Charm.Config().Options
+	names := make([]string, 0, len(options))
+	for name := range options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if strings.TrimSpace(options[name].Description) == "" {
+			ctx.Reportf("config.yaml", "option %q has no description", name)
+		}
+	}
+	return nil
+}
+
+func checkRelationHooks(ctx *Context) error {
+	files, err := hookFiles(ctx)
+	if err != nil {
+		return err
+	}
+	relations := ctx.Charm.Meta().CombinedRelations()
+	names := make([]string, 0, len(relations))
+	for name := range relations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		found := false
+		for _, kind := range hooks.RelationHooks() {
+			hookName := name + "-" + string(kind)
+			for _, info := range files {
+				if info.Name() == hookName {
+					found = true
+					break
+				}
+			}
+		}
+		if !found {
+			ctx.Reportf("metadata.yaml", "relation %q has no hook scripts", name)
+		}
+	}
+	return nil
+}
+
+func checkReadme(ctx *Context) error {
+	infos, err := ioutil.ReadDir(ctx.Path)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		if !info.IsDir() && strings.HasPrefix(strings.ToLower(info.Name()), "readme") {
+			return nil
+		}
+	}
+	ctx.Reportf("", "charm has no README file")
+	return nil
+}
+
+func checkIcon(ctx *Context) error {
+	info, err := os.Stat(filepath.Join(ctx.Path, "icon.svg"))
+	if os.IsNotExist(err) {
+		ctx.Reportf("", "charm has no icon.svg file")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		ctx.Reportf("icon.svg", "icon.svg is not a regular file")
+	}
+	return nil
+}
+
+func checkAbsoluteSymlinks(ctx *Context) error {
+	return filepath.Walk(ctx.Path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relpath, err := filepath.Rel(ctx.Path, path)
+		if err != nil {
+			return err
+		}
+		relpath = filepath.ToSlash(relpath)
+		// Top-level hidden directories are not included in charm archives.
+		if info.IsDir() && len(relpath) > 1 && relpath[0] == '.' {
+			return filepath.SkipDir
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			return nil
+		}
+		target, err := os.Readlink(path)
+		if err != nil {
+			return err
+		}
+		if filepath.IsAbs(target) {
+			ctx.Reportf(relpath, "symlink %q is absolute: %q", relpath, target)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,141 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package lint_test
+
+import (
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"gopkg.in/juju/charm.v6"
+	"gopkg.in/juju/charm.v6/lint"
+)
+
+type RulesSuite struct{}
+
+var _ = gc.Suite(&RulesSuite{})
+
+// lintWith runs the named rule against a charm holding the
+// given files and returns the reported violations.
+func lintWith(c *gc.C, rule string, files map[string]charmFile) []charm.Violation {
+	rules, err := lint.LookupRules(rule)
+	c.Assert(err, jc.ErrorIsNil)
+	report, err := lint.Lint(makeCharm(c, files), rules...)
+	c.Assert(err, jc.ErrorIsNil)
+	return report.Violations
+}
+
+func (s *RulesSuite) TestUnknownHook(c *gc.C) {
+	violations := lintWith(c, "unknown-hook", map[string]charmFile{
+		"hooks/install":             {mode: 0755},
+		"hooks/db-relation-changed": {mode: 0755},
+		"hooks/instal":              {mode: 0755},
+		"hooks/foo-relation-joined": {mode: 0755},
+		"hooks/lib/helpers.sh":      {},
+	})
+	c.Assert(violations, jc.DeepEquals, []charm.Violation{{
+		Code:     "unknown-hook",
+		Path:     "hooks/foo-relation-joined",
+		Severity: charm.SeverityWarning,
+		Message:  `"foo-relation-joined" is not a valid hook name for this charm`,
+	}, {
+		Code:     "unknown-hook",
+		Path:     "hooks/instal",
+		Severity: charm.SeverityWarning,
+		Message:  `"instal" is not a valid hook name for this charm`,
+	}})
+}
+
+func (s *RulesSuite) TestHookNotExecutable(c *gc.C) {
+	violations := lintWith(c, "hook-not-executable", map[string]charmFile{
+		"hooks/install":       {mode: 0755},
+		"hooks/start":         {mode: 0644},
+		"hooks/stop":          {link: "start"},
+		"hooks/config-data":   {mode: 0644},
+		"hooks/update-status": {link: "missing"},
+	})
+	c.Assert(violations, gc.HasLen, 3)
+	c.Assert(violations[:2], jc.DeepEquals, []charm.Violation{{
+		Code:     "hook-not-executable",
+		Path:     "hooks/start",
+		Severity: charm.SeverityError,
+		Message:  `hook "start" is not executable`,
+	}, {
+		Code:     "hook-not-executable",
+		Path:     "hooks/stop",
+		Severity: charm.SeverityError,
+		Message:  `hook "stop" is not executable`,
+	}})
+	c.Assert(violations[2].Path, gc.Equals, "hooks/update-status")
+	c.Assert(violations[2].Message, gc.Matches, `cannot read hook "update-status": .*`)
+}
+
+func (s *RulesSuite) TestConfigDescription(c *gc.C) {
+	violations := lintWith(c, "config-description", map[string]charmFile{
+		"config.yaml": {content: `
+options:
+  title: {type: string, description: The title.}
+  outlook: {type: string}
+  level: {type: int, description: "  "}
+`},
+	})
+	c.Assert(violations, gc.HasLen, 2)
+	c.Assert(violations[0].Message, gc.Equals, `option "level" has no description`)
+	c.Assert(violations[1].Message, gc.Equals, `option "outlook" has no description`)
+	c.Assert(violations[0].Path, gc.Equals, "config.yaml")
+}
+
+func (s *RulesSuite) TestRelationHooks(c *gc.C) {
+	violations := lintWith(c, "relation-hooks", map[string]charmFile{
+		"hooks/db-relation-broken": {mode: 0755},
+	})
+	c.Assert(violations, jc.DeepEquals, []charm.Violation{{
+		Code:     "relation-hooks",
+		Path:     "metadata.yaml",
+		Severity: charm.SeverityWarning,
+		Message:  `relation "website" has no hook scripts`,
+	}})
+}
+
+func (s *RulesSuite) TestReadme(c *gc.C) {
+	violations := lintWith(c, "readme", map[string]charmFile{})
+	c.Assert(violations, gc.HasLen, 1)
+	c.Assert(violations[0].Message, gc.Equals, "charm has no README file")
+
+	violations = lintWith(c, "readme", map[string]charmFile{
+		"README": {content: "hello"},
+	})
+	c.Assert(violations, gc.HasLen, 0)
+}
+
+func (s *RulesSuite) TestIcon(c *gc.C) {
+	violations := lintWith(c, "icon", map[string]charmFile{})
+	c.Assert(violations, gc.HasLen, 1)
+	c.Assert(violations[0].Message, gc.Equals, "charm has no icon.svg file")
+
+	violations = lintWith(c, "icon", map[string]charmFile{
+		"icon.svg": {content: "<svg/>"},
+	})
+	c.Assert(violations, gc.HasLen, 0)
+}
+
+func (s *RulesSuite) TestAbsoluteSymlink(c *gc.C) {
+	violations := lintWith(c, "absolute-symlink", map[string]charmFile{
+		"lib/relative":        {link: "../README"},
+		"lib/absolute":        {link: "/etc/passwd"},
+		".hidden/ignored":     {link: "/etc/passwd"},
+		"lib/.hidden/ignored": {link: "/etc/passwd"},
+	})
+	// Only top-level hidden directories are left out of archives.
+	c.Assert(violations, jc.DeepEquals, []charm.Violation{{
+		Code:     "absolute-symlink",
+		Path:     "lib/.hidden/ignored",
+		Severity: charm.SeverityError,
+		Message:  `symlink "lib/.hidden/ignored" is absolute: "/etc/passwd"`,
+	}, {
+		Code:     "absolute-symlink",
+		Path:     "lib/absolute",
+		Severity: charm.SeverityError,
+		Message:  `symlink "lib/absolute" is absolute: "/etc/passwd"`,
+	}})
+}
@@ -591,130 +591,15 @@ func marshaledExtraBindings(bindings map[string]ExtraBinding) map[string]interfa
 	return marshaled
 }
 
-// Check checks that the metadata is well-formed.
+// Check checks that the metadata is well-formed. It returns
+// the first problem found; use CheckAll to find all of them.
 func (meta Meta) Check() error {
-	// Check for duplicate or forbidden relation names or interfaces.
-	names := map[string]bool{}
-	checkRelations := func(src map[string]Relation, role RelationRole) error {
-		for name, rel := range src {
-			if rel.Name != name {
-				return fmt.Errorf("charm %q has mismatched relation name %q; expected %q", meta.Name, rel.Name, name)
-			}
-			if rel.Role != role {
-				return fmt.Errorf("charm %q has mismatched role %q; expected %q", meta.Name, rel.Role, role)
-			}
-			// Container-scoped require relations on subordinates are allowed
-			// to use the otherwise-reserved juju-* namespace.
-			if !meta.Subordinate || role != RoleRequirer || rel.Scope != ScopeContainer {
-				if reserved, _ := reservedName(name); reserved {
-					return fmt.Errorf("charm %q using a reserved relation name: %q", meta.Name, name)
-				}
-			}
-			if role != RoleRequirer {
-				if reserved, _ := reservedName(rel.Interface); reserved {
-					return fmt.Errorf("charm %q relation %q using a reserved interface: %q", meta.Name, name, rel.Interface)
-				}
-			}
-			if names[name] {
-				return fmt.Errorf("charm %q using a duplicated relation name: %q", meta.Name, name)
-			}
-			names[name] = true
+	checker := meta.check()
+	for i, v := range checker.violations {
+		if v.Severity == SeverityError {
+			return checker.causes[i]
 		}
-		return nil
-	}
-	if err := checkRelations(meta.Provides, RoleProvider); err != nil {
-		return err
-	}
-	if err := checkRelations(meta.Requires, RoleRequirer); err != nil {
-		return err
-	}
-	if err := checkRelations(meta.Peers, RolePeer); err != nil {
-		return err
 	}
-
-	if err := validateMetaExtraBindings(meta); err != nil {
-		return fmt.Errorf("charm %q has invalid extra bindings: %v", meta.Name, err)
-	}
-
-	// Subordinate charms must have at least one relation that
-	// has container scope, otherwise they can't relate to the
-	// principal.
-	if meta.Subordinate {
-		valid := false
-		if meta.Requires != nil {
-			for _, relationData := range meta.Requires {
-				if relationData.Scope == ScopeContainer {
-					valid = true
-					break
-				}
-			}
-		}
-		if !valid {
-			return fmt.Errorf("subordinate charm %q lacks \"requires\" relation with container scope", meta.Name)
-		}
-	}
-
-	for _, series := range meta.Series {
-		if !IsValidSeries(series) {
-			return fmt.Errorf("charm %q declares invalid series: %q", meta.Name, series)
-		}
-	}
-
-	names = make(map[string]bool)
-	for name, store := range meta.Storage {
-		if store.Location != "" && store.Type != StorageFilesystem {
-			return fmt.Errorf(`charm %q storage %q: location may not be specified for "type: %s"`, meta.Name, name, store.Type)
-		}
-		if store.Type == "" {
-			return fmt.Errorf("charm %q storage %q: type must be specified", meta.Name, name)
-		}
-		if store.CountMin < 0 {
-			return fmt.Errorf("charm %q storage %q: invalid minimum count %d", meta.Name, name, store.CountMin)
-		}
-		if store.CountMax == 0 || store.CountMax < -1 {
-			return fmt.Errorf("charm %q storage %q: invalid maximum count %d", meta.Name, name, store.CountMax)
-		}
-		if names[name] {
-			return fmt.Errorf("charm %q storage %q: duplicated storage name", meta.Name, name)
-		}
-		names[name] = true
-	}
-
-	names = make(map[string]bool)
-	for name, device := range meta.Devices {
-		if device.Type == "" {
-			return fmt.Errorf("charm %q device %q: type must be specified", meta.Name, name)
-		}
-		if device.CountMax >= 0 && device.CountMin >= 0 && device.CountMin > device.CountMax {
-			return fmt.Errorf(
-				"charm %q device %q: maximum count %d can not be smaller than minimum count %d",
-				meta.Name, name, device.CountMax, device.CountMin)
-		}
-		if names[name] {
-			return fmt.Errorf("charm %q device %q: duplicated device name", meta.Name, name)
-		}
-		names[name] = true
-	}
-
-	for name, payloadClass := range meta.PayloadClasses {
-		if payloadClass.Name != name {
-			return fmt.Errorf("mismatch on payload class name (%q != %q)", payloadClass.Name, name)
-		}
-		if err := payloadClass.Validate(); err != nil {
-			return err
-		}
-	}
-
-	if err := validateMetaResources(meta.Resources); err != nil {
-		return err
-	}
-
-	for _, term := range meta.Terms {
-		if _, terr := ParseTerm(term); terr != nil {
-			return errors.Trace(terr)
-		}
-	}
-
 	return nil
 }
 
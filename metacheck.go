@@ -0,0 +1,305 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package charm
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/juju/errors"
+)
+
+// Severity describes how serious a reported violation is.
+type Severity string
+
+const (
+	// SeverityError marks a violation that makes the charm unusable.
+	SeverityError Severity = "error"
+
+	// SeverityWarning marks a violation that does not prevent the
+	// charm from being used, but probably indicates a mistake.
+	SeverityWarning Severity = "warning"
+)
+
+// Codes of the violations reported by Meta.CheckAll.
+const (
+	CodeRelationNameMismatch     = "relation-name-mismatch"
+	CodeRelationRoleMismatch     = "relation-role-mismatch"
+	CodeRelationReservedName     = "relation-reserved-name"
+	CodeRelationReservedIface    = "relation-reserved-interface"
+	CodeRelationDuplicateName    = "relation-duplicate-name"
+	CodeExtraBindingsInvalid     = "extra-bindings-invalid"
+	CodeSubordinateNoContainer   = "subordinate-no-container-relation"
+	CodeSeriesInvalid            = "series-invalid"
+	CodeStorageLocation          = "storage-location"
+	CodeStorageTypeMissing       = "storage-type-missing"
+	CodeStorageCountMin          = "storage-count-min"
+	CodeStorageCountMax          = "storage-count-max"
+	CodeDeviceTypeMissing        = "device-type-missing"
+	CodeDeviceCountRange         = "device-count-range"
+	CodePayloadClassNameMismatch = "payload-class-name-mismatch"
+	CodePayloadClassInvalid      = "payload-class-invalid"
+	CodeResourceNameMismatch     = "resource-name-mismatch"
+	CodeResourceInvalid          = "resource-invalid"
+	CodeTermInvalid              = "term-invalid"
+)
+
+// Violation describes a single problem found when checking charm data.
+type Violation struct {
+	// Code identifies the kind of problem, for example
+	// "storage-count-max".
+	Code string
+
+	// Path holds the dotted path of the offending field,
+	// for example "storage.data.multiple".
+	Path string
+
+	// Severity holds how serious the problem is.
+	Severity Severity
+
+	// Message holds a human readable description of the problem.
+	Message string
+}
+
+// Error implements the error interface.
+func (v Violation) Error() string {
+	return v.Message
+}
+
+// MetaCheckError holds all the violations found by Meta.CheckAll.
+type MetaCheckError struct {
+	Violations []Violation
+}
+
+func (err *MetaCheckError) Error() string {
+	switch len(err.Violations) {
+	case 0:
+		return "no metadata violations!"
+	case 1:
+		return err.Violations[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", err.Violations[0], len(err.Violations)-1)
+}
+
+// metaChecker accumulates the violations found in a Meta.
+type metaChecker struct {
+	meta Meta
+
+	violations []Violation
+
+	// causes holds, for each violation, the error that
+	// originally reported it.
+	causes []error
+}
+
+func (checker *metaChecker) addf(code, path string, f string, a ...interface{}) {
+	checker.add(code, path, fmt.Errorf(f, a...))
+}
+
+func (checker *metaChecker) add(code, path string, err error) {
+	checker.violations = append(checker.violations, Violation{
+		Code:     code,
+		Path:     path,
+		Severity: SeverityError,
+		Message:  err.Error(),
+	})
+	checker.causes = append(checker.causes, err)
+}
+
+// CheckAll checks that the metadata is well-formed, like Check, but
+// does not stop at the first problem. If any problems are found,
+// it returns a *MetaCheckError describing all of them.
+func (meta Meta) CheckAll() error {
+	checker := meta.check()
+	if len(checker.violations) > 0 {
+		return &MetaCheckError{checker.violations}
+	}
+	return nil
+}
+
+func (meta Meta) check() *metaChecker {
+	checker := &metaChecker{meta: meta}
+	checker.checkRelations()
+	checker.checkExtraBindings()
+	checker.checkSubordinate()
+	checker.checkSeries()
+	checker.checkStorage()
+	checker.checkDevices()
+	checker.checkPayloadClasses()
+	checker.checkResources()
+	checker.checkTerms()
+	return checker
+}
+
+// checkRelations checks for duplicate or forbidden relation names
+// or interfaces.
+func (checker *metaChecker) checkRelations() {
+	meta := checker.meta
+	names := map[string]bool{}
+	check := func(section string, src map[string]Relation, role RelationRole) {
+		for _, name := range sortedRelationNames(src) {
+			rel := src[name]
+			path := section + "." + name
+			if rel.Name != name {
+				checker.addf(CodeRelationNameMismatch, path, "charm %q has mismatched relation name %q; expected %q", meta.Name, rel.Name, name)
+			}
+			if rel.Role != role {
+				checker.addf(CodeRelationRoleMismatch, path, "charm %q has mismatched role %q; expected %q", meta.Name, rel.Role, role)
+			}
+			// Container-scoped require relations on subordinates are allowed
+			// to use the otherwise-reserved juju-* namespace.
+			if !meta.Subordinate || role != RoleRequirer || rel.Scope != ScopeContainer {
+				if reserved, _ := reservedName(name); reserved {
+					checker.addf(CodeRelationReservedName, path, "charm %q using a reserved relation name: %q", meta.Name, name)
+				}
+			}
+			if role != RoleRequirer {
+				if reserved, _ := reservedName(rel.Interface); reserved {
+					checker.addf(CodeRelationReservedIface, path+".interface", "charm %q relation %q using a reserved interface: %q", meta.Name, name, rel.Interface)
+				}
+			}
+			if names[name] {
+				checker.addf(CodeRelationDuplicateName, path, "charm %q using a duplicated relation name: %q", meta.Name, name)
+			}
+			names[name] = true
+		}
+	}
+	check("provides", meta.Provides, RoleProvider)
+	check("requires", meta.Requires, RoleRequirer)
+	check("peers", meta.Peers, RolePeer)
+}
+
+func (checker *metaChecker) checkExtraBindings() {
+	if err := validateMetaExtraBindings(checker.meta); err != nil {
+		checker.addf(CodeExtraBindingsInvalid, "extra-bindings", "charm %q has invalid extra bindings: %v", checker.meta.Name, err)
+	}
+}
+
+// checkSubordinate checks that subordinate charms have at least one
+// relation that has container scope, otherwise they can't relate to
+// the principal.
+func (checker *metaChecker) checkSubordinate() {
+	meta := checker.meta
+	if !meta.Subordinate {
+		return
+	}
+	for _, relationData := range meta.Requires {
+		if relationData.Scope == ScopeContainer {
+			return
+		}
+	}
+	checker.addf(CodeSubordinateNoContainer, "requires", "subordinate charm %q lacks \"requires\" relation with container scope", meta.Name)
+}
+
+func (checker *metaChecker) checkSeries() {
+	meta := checker.meta
+	for i, series := range meta.Series {
+		if !IsValidSeries(series) {
+			checker.addf(CodeSeriesInvalid, fmt.Sprintf("series[%d]", i), "charm %q declares invalid series: %q", meta.Name, series)
+		}
+	}
+}
+
+func (checker *metaChecker) checkStorage() {
+	meta := checker.meta
+	names := make([]string, 0, len(meta.Storage))
+	for name := range meta.Storage {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		store := meta.Storage[name]
+		path := "storage." + name
+		if store.Location != "" && store.Type != StorageFilesystem {
+			checker.addf(CodeStorageLocation, path+".location", `charm %q storage %q: location may not be specified for "type: %s"`, meta.Name, name, store.Type)
+		}
+		if store.Type == "" {
+			checker.addf(CodeStorageTypeMissing, path+".type", "charm %q storage %q: type must be specified", meta.Name, name)
+		}
+		if store.CountMin < 0 {
+			checker.addf(CodeStorageCountMin, path+".multiple", "charm %q storage %q: invalid minimum count %d", meta.Name, name, store.CountMin)
+		}
+		if store.CountMax == 0 || store.CountMax < -1 {
+			checker.addf(CodeStorageCountMax, path+".multiple", "charm %q storage %q: invalid maximum count %d", meta.Name, name, store.CountMax)
+		}
+	}
+}
+
+func (checker *metaChecker) checkDevices() {
+	meta := checker.meta
+	names := make([]string, 0, len(meta.Devices))
+	for name := range meta.Devices {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		device := meta.Devices[name]
+		path := "devices." + name
+		if device.Type == "" {
+			checker.addf(CodeDeviceTypeMissing, path+".type", "charm %q device %q: type must be specified", meta.Name, name)
+		}
+		if device.CountMax >= 0 && device.CountMin >= 0 && device.CountMin > device.CountMax {
+			checker.addf(CodeDeviceCountRange, path+".countmax",
+				"charm %q device %q: maximum count %d can not be smaller than minimum count %d",
+				meta.Name, name, device.CountMax, device.CountMin)
+		}
+	}
+}
+
+func (checker *metaChecker) checkPayloadClasses() {
+	meta := checker.meta
+	names := make([]string, 0, len(meta.PayloadClasses))
+	for name := range meta.PayloadClasses {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		payloadClass := meta.PayloadClasses[name]
+		path := "payloads." + name
+		if payloadClass.Name != name {
+			checker.addf(CodePayloadClassNameMismatch, path, "mismatch on payload class name (%q != %q)", payloadClass.Name, name)
+			continue
+		}
+		if err := payloadClass.Validate(); err != nil {
+			checker.add(CodePayloadClassInvalid, path, err)
+		}
+	}
+}
+
+func (checker *metaChecker) checkResources() {
+	meta := checker.meta
+	names := make([]string, 0, len(meta.Resources))
+	for name := range meta.Resources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		res := meta.Resources[name]
+		path := "resources." + name
+		if res.Name != name {
+			checker.addf(CodeResourceNameMismatch, path, "mismatch on resource name (%q != %q)", res.Name, name)
+			continue
+		}
+		if err := res.Validate(); err != nil {
+			checker.add(CodeResourceInvalid, path, err)
+		}
+	}
+}
+
+func (checker *metaChecker) checkTerms() {
+	for i, term := range checker.meta.Terms {
+		if _, err := ParseTerm(term); err != nil {
+			checker.add(CodeTermInvalid, fmt.Sprintf("terms[%d]", i), errors.Trace(err))
+		}
+	}
+}
+
+func sortedRelationNames(relations map[string]Relation) []string {
+	names := make([]string, 0, len(relations))
+	for name := range relations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
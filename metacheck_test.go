@@ -0,0 +1,124 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package charm_test
+
+import (
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"gopkg.in/juju/charm.v6"
+	"gopkg.in/juju/charm.v6/resource"
+)
+
+type MetaCheckSuite struct{}
+
+var _ = gc.Suite(&MetaCheckSuite{})
+
+func (s *MetaCheckSuite) TestCheckAllValid(c *gc.C) {
+	meta := readCharmDir(c, "dummy").Meta()
+	c.Assert(meta.CheckAll(), jc.ErrorIsNil)
+}
+
+func (s *MetaCheckSuite) TestCheckAllCollectsEveryViolation(c *gc.C) {
+	meta := charm.Meta{
+		Name:        "foo",
+		Subordinate: true,
+		Provides: map[string]charm.Relation{
+			"juju-db": {
+				Name:      "juju-db",
+				Role:      charm.RoleProvider,
+				Interface: "juju-thing",
+				Scope:     charm.ScopeGlobal,
+			},
+		},
+		Requires: map[string]charm.Relation{
+			"db": {
+				Name:      "db",
+				Role:      charm.RolePeer,
+				Interface: "mysql",
+				Scope:     charm.ScopeGlobal,
+			},
+		},
+		Series: []string{"precise", "!bad"},
+		Storage: map[string]charm.Storage{
+			"data": {
+				Name:     "data",
+				Type:     charm.StorageBlock,
+				Location: "/srv",
+				CountMin: 1,
+				CountMax: 0,
+			},
+		},
+		Devices: map[string]charm.Device{
+			"gpu": {
+				Name:     "gpu",
+				Type:     "gpu",
+				CountMin: 2,
+				CountMax: 1,
+			},
+		},
+		PayloadClasses: map[string]charm.PayloadClass{
+			"monitor": {Name: "monitor"},
+		},
+		Resources: map[string]resource.Meta{
+			"blob": {Name: "other", Type: resource.TypeFile, Path: "blob.tgz"},
+		},
+		Terms: []string{"term/1", "!!!"},
+	}
+	err := meta.CheckAll()
+	c.Assert(err, gc.FitsTypeOf, &charm.MetaCheckError{})
+	violations := err.(*charm.MetaCheckError).Violations
+
+	type summary struct {
+		code string
+		path string
+	}
+	var got []summary
+	for _, v := range violations {
+		c.Check(v.Severity, gc.Equals, charm.SeverityError)
+		c.Check(v.Message, gc.Not(gc.Equals), "")
+		got = append(got, summary{v.Code, v.Path})
+	}
+	c.Assert(got, jc.DeepEquals, []summary{
+		{charm.CodeRelationReservedName, "provides.juju-db"},
+		{charm.CodeRelationReservedIface, "provides.juju-db.interface"},
+		{charm.CodeRelationRoleMismatch, "requires.db"},
+		{charm.CodeSubordinateNoContainer, "requires"},
+		{charm.CodeSeriesInvalid, "series[1]"},
+		{charm.CodeStorageLocation, "storage.data.location"},
+		{charm.CodeStorageCountMax, "storage.data.multiple"},
+		{charm.CodeDeviceCountRange, "devices.gpu.countmax"},
+		{charm.CodePayloadClassInvalid, "payloads.monitor"},
+		{charm.CodeResourceNameMismatch, "resources.blob"},
+		{charm.CodeTermInvalid, "terms[1]"},
+	})
+	c.Assert(err, gc.ErrorMatches, `charm "foo" using a reserved relation name: "juju-db" \(and 10 more errors\)`)
+}
+
+func (s *MetaCheckSuite) TestCheckReturnsFirstViolation(c *gc.C) {
+	meta := charm.Meta{
+		Name: "foo",
+		Storage: map[string]charm.Storage{
+			"a": {Name: "a"},
+			"b": {Name: "b", Type: charm.StorageBlock, CountMax: -2},
+		},
+	}
+	err := meta.Check()
+	c.Assert(err, gc.ErrorMatches, `charm "foo" storage "a": type must be specified`)
+
+	err = meta.CheckAll()
+	c.Assert(err, gc.ErrorMatches, `charm "foo" storage "a": type must be specified \(and 2 more errors\)`)
+}
+
+func (s *MetaCheckSuite) TestCheckPreservesCause(c *gc.C) {
+	meta := charm.Meta{
+		Name: "foo",
+		Resources: map[string]resource.Meta{
+			"blob": {Name: "blob"},
+		},
+	}
+	err := meta.Check()
+	c.Assert(err, gc.ErrorMatches, "resource missing type")
+	c.Assert(err, gc.Not(gc.FitsTypeOf), charm.Violation{})
+}
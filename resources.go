@@ -4,8 +4,6 @@
 package charm
 
 import (
-	"fmt"
-
 	"github.com/juju/errors"
 	"github.com/juju/schema"
 
@@ -42,18 +40,6 @@ func parseMetaResources(data interface{}) (map[string]resource.Meta, error) {
 	return result, nil
 }
 
-func validateMetaResources(resources map[string]resource.Meta) error {
-	for name, res := range resources {
-		if res.Name != name {
-			return fmt.Errorf("mismatch on resource name (%q != %q)", res.Name, name)
-		}
-		if err := res.Validate(); err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
 // parseResourceMeta parses the provided data into a Meta, assuming
 // that the data has first been checked with resourceSchema.
 func parseResourceMeta(name string, data interface{}) (resource.Meta, error) {
@@ -0,0 +1,168 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package charm
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// SignatureFileName holds the name of the archive entry that holds
+// the signature of a charm archive. The entry is not part of the
+// content covered by the archive's ContentDigest.
+const SignatureFileName = ".signature"
+
+// signatureAlgorithm holds the only supported signature algorithm.
+const signatureAlgorithm = "ed25519"
+
+// signaturePrefix is prepended to the content digest to form the
+// signed message, so that a signature made for another purpose with
+// the same key cannot be mistaken for a charm signature.
+const signaturePrefix = "juju-charm-content-digest:"
+
+// archiveSignature holds the content of a signature entry.
+type archiveSignature struct {
+	Algorithm string `json:"algorithm"`
+	PublicKey []byte `json:"public-key"`
+	Digest    string `json:"digest"`
+	Signature []byte `json:"signature"`
+}
+
+// SignCharmArchive writes a copy of the given archive to w, with a
+// signature entry holding the signature of the archive's content
+// digest made with the given key. Any existing signature is replaced.
+func SignCharmArchive(w io.Writer, archive *CharmArchive, key ed25519.PrivateKey) error {
+	digest, err := archive.ContentDigest()
+	if err != nil {
+		return err
+	}
+	sig, err := json.Marshal(archiveSignature{
+		Algorithm: signatureAlgorithm,
+		PublicKey: key.Public().(ed25519.PublicKey),
+		Digest:    digest,
+		Signature: ed25519.Sign(key, []byte(signaturePrefix+digest)),
+	})
+	if err != nil {
+		return err
+	}
+	zipr, err := archive.zopen.openZip()
+	if err != nil {
+		return err
+	}
+	defer zipr.Close()
+	zipw := zip.NewWriter(w)
+	for _, f := range zipr.File {
+		if f.Name == SignatureFileName {
+			continue
+		}
+		if err := copyZipFile(zipw, f); err != nil {
+			zipw.Close()
+			return err
+		}
+	}
+	h := &zip.FileHeader{
+		Name:   SignatureFileName,
+		Method: zip.Deflate,
+	}
+	h.SetMode(0644)
+	sigw, err := zipw.CreateHeader(h)
+	if err != nil {
+		zipw.Close()
+		return err
+	}
+	if _, err := sigw.Write(sig); err != nil {
+		zipw.Close()
+		return err
+	}
+	return zipw.Close()
+}
+
+// copyZipFile copies the given file to zipw.
+func copyZipFile(zipw *zip.Writer, f *zip.File) error {
+	h := f.FileHeader
+	w, err := zipw.CreateHeader(&h)
+	if err != nil {
+		return err
+	}
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	_, err = io.Copy(w, r)
+	return err
+}
+
+// notSignedError is returned by VerifyCharmArchive
+// when the archive holds no signature.
+var notSignedError = fmt.Errorf("charm archive is not signed")
+
+// IsNotSignedError returns true if err was returned because
+// a charm archive holds no signature.
+func IsNotSignedError(err error) bool {
+	return err == notSignedError
+}
+
+// VerifyCharmArchive checks that the given archive has been signed
+// with one of the trusted keys by SignCharmArchive, and that its
+// content has not been modified since. If the archive is not signed,
+// it returns an error that satisfies IsNotSignedError.
+func VerifyCharmArchive(archive *CharmArchive, trustedKeys []ed25519.PublicKey) error {
+	data, err := archive.readFile(SignatureFileName)
+	if _, ok := err.(*noCharmArchiveFile); ok {
+		return notSignedError
+	}
+	if err != nil {
+		return err
+	}
+	var sig archiveSignature
+	if err := json.Unmarshal(data, &sig); err != nil {
+		return fmt.Errorf("cannot parse charm archive signature: %v", err)
+	}
+	if sig.Algorithm != signatureAlgorithm {
+		return fmt.Errorf("unsupported signature algorithm %q", sig.Algorithm)
+	}
+	trusted := false
+	for _, key := range trustedKeys {
+		if bytes.Equal(key, sig.PublicKey) {
+			trusted = true
+			break
+		}
+	}
+	if !trusted {
+		return fmt.Errorf("charm archive signed with untrusted key")
+	}
+	if len(sig.PublicKey) != ed25519.PublicKeySize ||
+		!ed25519.Verify(ed25519.PublicKey(sig.PublicKey), []byte(signaturePrefix+sig.Digest), sig.Signature) {
+		return fmt.Errorf("invalid charm archive signature")
+	}
+	digest, err := archive.ContentDigest()
+	if err != nil {
+		return err
+	}
+	if digest != sig.Digest {
+		return fmt.Errorf("charm archive content has been modified since it was signed")
+	}
+	return nil
+}
+
+// readFile returns the content of the named file in the archive.
+func (a *CharmArchive) readFile(name string) ([]byte, error) {
+	zipr, err := a.zopen.openZip()
+	if err != nil {
+		return nil, err
+	}
+	defer zipr.Close()
+	r, err := zipOpenFile(zipr, name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
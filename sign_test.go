@@ -0,0 +1,152 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package charm_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/ed25519"
+	"io"
+	"io/ioutil"
+
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"gopkg.in/juju/charm.v6"
+)
+
+type SignSuite struct {
+	data []byte
+	key  ed25519.PrivateKey
+}
+
+var _ = gc.Suite(&SignSuite{})
+
+func (s *SignSuite) SetUpSuite(c *gc.C) {
+	var err error
+	s.data, err = ioutil.ReadFile(archivePath(c, readCharmDir(c, "dummy")))
+	c.Assert(err, jc.ErrorIsNil)
+	s.key = newSigningKey(1)
+}
+
+func newSigningKey(seed byte) ed25519.PrivateKey {
+	return ed25519.NewKeyFromSeed(bytes.Repeat([]byte{seed}, ed25519.SeedSize))
+}
+
+func publicKey(key ed25519.PrivateKey) ed25519.PublicKey {
+	return key.Public().(ed25519.PublicKey)
+}
+
+func (s *SignSuite) sign(c *gc.C, data []byte, key ed25519.PrivateKey) []byte {
+	archive, err := charm.ReadCharmArchiveBytes(data)
+	c.Assert(err, jc.ErrorIsNil)
+	var buf bytes.Buffer
+	err = charm.SignCharmArchive(&buf, archive, key)
+	c.Assert(err, jc.ErrorIsNil)
+	return buf.Bytes()
+}
+
+func (s *SignSuite) verify(c *gc.C, data []byte, keys ...ed25519.PublicKey) error {
+	archive, err := charm.ReadCharmArchiveBytes(data)
+	c.Assert(err, jc.ErrorIsNil)
+	return charm.VerifyCharmArchive(archive, keys)
+}
+
+func (s *SignSuite) TestSignAndVerify(c *gc.C) {
+	signed := s.sign(c, s.data, s.key)
+	other := newSigningKey(2)
+	err := s.verify(c, signed, publicKey(other), publicKey(s.key))
+	c.Assert(err, jc.ErrorIsNil)
+
+	// The signed archive is still a valid charm with the same content.
+	archive, err := charm.ReadCharmArchiveBytes(signed)
+	c.Assert(err, jc.ErrorIsNil)
+	checkDummy(c, archive, "")
+	original, err := charm.ReadCharmArchiveBytes(s.data)
+	c.Assert(err, jc.ErrorIsNil)
+	originalDigest, err := original.ContentDigest()
+	c.Assert(err, jc.ErrorIsNil)
+	signedDigest, err := archive.ContentDigest()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(signedDigest, gc.Equals, originalDigest)
+}
+
+func (s *SignSuite) TestNotSigned(c *gc.C) {
+	err := s.verify(c, s.data, publicKey(s.key))
+	c.Assert(err, gc.ErrorMatches, "charm archive is not signed")
+	c.Assert(charm.IsNotSignedError(err), jc.IsTrue)
+}
+
+func (s *SignSuite) TestUntrustedKey(c *gc.C) {
+	signed := s.sign(c, s.data, s.key)
+	err := s.verify(c, signed, publicKey(newSigningKey(2)))
+	c.Assert(err, gc.ErrorMatches, "charm archive signed with untrusted key")
+	c.Assert(charm.IsNotSignedError(err), jc.IsFalse)
+}
+
+func (s *SignSuite) TestResign(c *gc.C) {
+	other := newSigningKey(2)
+	signed := s.sign(c, s.sign(c, s.data, s.key), other)
+	err := s.verify(c, signed, publicKey(s.key))
+	c.Assert(err, gc.ErrorMatches, "charm archive signed with untrusted key")
+	err = s.verify(c, signed, publicKey(other))
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+// rewriteArchive returns a copy of the given archive, with the content
+// of each file passed through the given function.
+func rewriteArchive(c *gc.C, data []byte, rewrite func(name string, content []byte) []byte) []byte {
+	zipr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	c.Assert(err, jc.ErrorIsNil)
+	var buf bytes.Buffer
+	zipw := zip.NewWriter(&buf)
+	for _, f := range zipr.File {
+		h := f.FileHeader
+		w, err := zipw.CreateHeader(&h)
+		c.Assert(err, jc.ErrorIsNil)
+		r, err := f.Open()
+		c.Assert(err, jc.ErrorIsNil)
+		content, err := ioutil.ReadAll(r)
+		r.Close()
+		c.Assert(err, jc.ErrorIsNil)
+		_, err = io.Copy(w, bytes.NewReader(rewrite(f.Name, content)))
+		c.Assert(err, jc.ErrorIsNil)
+	}
+	c.Assert(zipw.Close(), jc.ErrorIsNil)
+	return buf.Bytes()
+}
+
+func (s *SignSuite) TestModifiedContent(c *gc.C) {
+	signed := s.sign(c, s.data, s.key)
+	modified := rewriteArchive(c, signed, func(name string, content []byte) []byte {
+		if name == "hooks/install" {
+			return []byte("#!/bin/sh\nrm -rf /\n")
+		}
+		return content
+	})
+	err := s.verify(c, modified, publicKey(s.key))
+	c.Assert(err, gc.ErrorMatches, "charm archive content has been modified since it was signed")
+}
+
+func (s *SignSuite) TestModifiedSignature(c *gc.C) {
+	signed := s.sign(c, s.data, s.key)
+	modified := rewriteArchive(c, signed, func(name string, content []byte) []byte {
+		if name == charm.SignatureFileName {
+			// Claim a different digest.
+			return bytes.Replace(content, []byte(`"digest":"`), []byte(`"digest":"0`), 1)
+		}
+		return content
+	})
+	err := s.verify(c, modified, publicKey(s.key))
+	c.Assert(err, gc.ErrorMatches, "invalid charm archive signature")
+
+	modified = rewriteArchive(c, signed, func(name string, content []byte) []byte {
+		if name == charm.SignatureFileName {
+			return []byte("garbage")
+		}
+		return content
+	})
+	err = s.verify(c, modified, publicKey(s.key))
+	c.Assert(err, gc.ErrorMatches, "cannot parse charm archive signature: .*")
+}
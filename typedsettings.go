@@ -0,0 +1,140 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package charm
+
+import (
+	"fmt"
+	"time"
+)
+
+// TypedSettings provides typed access to settings for the options of a
+// config. Settings that are missing or nil fall back to the default
+// value of their option.
+type TypedSettings struct {
+	config   *Config
+	settings Settings
+}
+
+// NewTypedSettings returns a typed view of the given settings for the
+// options of the given config. The settings are usually those returned
+// by Config.ValidateSettings or Config.Merge, but values of other
+// compatible types are converted as by ValidateSettings.
+func NewTypedSettings(config *Config, settings Settings) *TypedSettings {
+	return &TypedSettings{
+		config:   config,
+		settings: settings,
+	}
+}
+
+// notSetError is returned when an option has neither a value nor a default.
+type notSetError struct {
+	name string
+}
+
+func (err *notSetError) Error() string {
+	return fmt.Sprintf("option %q is not set and has no default", err.name)
+}
+
+// IsNotSetError returns true if err was returned because an option
+// has neither a value nor a default.
+func IsNotSetError(err error) bool {
+	_, ok := err.(*notSetError)
+	return ok
+}
+
+// value returns the value of the named option, which must have one
+// of the given types.
+func (s *TypedSettings) value(name string, types ...string) (interface{}, error) {
+	option, err := s.config.option(name)
+	if err != nil {
+		return nil, err
+	}
+	ok := false
+	for _, t := range types {
+		ok = ok || option.Type == t
+	}
+	if !ok {
+		return nil, fmt.Errorf("option %q has type %q, not %s", name, option.Type, types[0])
+	}
+	value := s.settings[name]
+	if value == nil {
+		value = option.Default
+	}
+	if value == nil {
+		return nil, &notSetError{name}
+	}
+	return option.validate(name, value)
+}
+
+// String returns the value of the named string, secret or enum option.
+func (s *TypedSettings) String(name string) (string, error) {
+	value, err := s.value(name, "string", "secret", "enum")
+	if err != nil {
+		return "", err
+	}
+	return value.(string), nil
+}
+
+// Int returns the value of the named int option.
+func (s *TypedSettings) Int(name string) (int64, error) {
+	value, err := s.value(name, "int")
+	if err != nil {
+		return 0, err
+	}
+	return value.(int64), nil
+}
+
+// Float returns the value of the named float option.
+func (s *TypedSettings) Float(name string) (float64, error) {
+	value, err := s.value(name, "float")
+	if err != nil {
+		return 0, err
+	}
+	return value.(float64), nil
+}
+
+// Bool returns the value of the named boolean option.
+func (s *TypedSettings) Bool(name string) (bool, error) {
+	value, err := s.value(name, "boolean")
+	if err != nil {
+		return false, err
+	}
+	return value.(bool), nil
+}
+
+// Duration returns the value of the named duration option.
+func (s *TypedSettings) Duration(name string) (time.Duration, error) {
+	value, err := s.value(name, "duration")
+	if err != nil {
+		return 0, err
+	}
+	return value.(time.Duration), nil
+}
+
+// Bytes returns the value of the named bytes option.
+func (s *TypedSettings) Bytes(name string) (int64, error) {
+	value, err := s.value(name, "bytes")
+	if err != nil {
+		return 0, err
+	}
+	return value.(int64), nil
+}
+
+// List returns the value of the named list option.
+func (s *TypedSettings) List(name string) ([]interface{}, error) {
+	value, err := s.value(name, "list")
+	if err != nil {
+		return nil, err
+	}
+	return value.([]interface{}), nil
+}
+
+// Map returns the value of the named map option.
+func (s *TypedSettings) Map(name string) (map[string]interface{}, error) {
+	value, err := s.value(name, "map")
+	if err != nil {
+		return nil, err
+	}
+	return value.(map[string]interface{}), nil
+}
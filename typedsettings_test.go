@@ -0,0 +1,125 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package charm_test
+
+import (
+	"strings"
+	"time"
+
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"gopkg.in/juju/charm.v6"
+)
+
+type TypedSettingsSuite struct {
+	settings *charm.TypedSettings
+}
+
+var _ = gc.Suite(&TypedSettingsSuite{})
+
+func (s *TypedSettingsSuite) SetUpTest(c *gc.C) {
+	config, err := charm.ReadConfig(strings.NewReader(`
+options:
+    name:
+        type: string
+        default: app
+    password:
+        type: secret
+    level:
+        type: enum
+        allowed: [debug, info]
+        default: info
+    port:
+        type: int
+        default: 80
+    ratio:
+        type: float
+    debug:
+        type: boolean
+        default: false
+    timeout:
+        type: duration
+        default: 1m
+    cache:
+        type: bytes
+    hosts:
+        type: list
+    labels:
+        type: map
+`))
+	c.Assert(err, gc.IsNil)
+	s.settings = charm.NewTypedSettings(config, charm.Settings{
+		"name":   nil,
+		"port":   8080,
+		"ratio":  0.5,
+		"debug":  true,
+		"cache":  "1K",
+		"hosts":  []interface{}{"a", "b"},
+		"labels": map[string]interface{}{"k": "v"},
+	})
+}
+
+func (s *TypedSettingsSuite) TestValues(c *gc.C) {
+	name, err := s.settings.String("name")
+	c.Assert(err, gc.IsNil)
+	c.Assert(name, gc.Equals, "app")
+
+	level, err := s.settings.String("level")
+	c.Assert(err, gc.IsNil)
+	c.Assert(level, gc.Equals, "info")
+
+	port, err := s.settings.Int("port")
+	c.Assert(err, gc.IsNil)
+	c.Assert(port, gc.Equals, int64(8080))
+
+	ratio, err := s.settings.Float("ratio")
+	c.Assert(err, gc.IsNil)
+	c.Assert(ratio, gc.Equals, 0.5)
+
+	debug, err := s.settings.Bool("debug")
+	c.Assert(err, gc.IsNil)
+	c.Assert(debug, jc.IsTrue)
+
+	timeout, err := s.settings.Duration("timeout")
+	c.Assert(err, gc.IsNil)
+	c.Assert(timeout, gc.Equals, time.Minute)
+
+	cache, err := s.settings.Bytes("cache")
+	c.Assert(err, gc.IsNil)
+	c.Assert(cache, gc.Equals, int64(1024))
+
+	hosts, err := s.settings.List("hosts")
+	c.Assert(err, gc.IsNil)
+	c.Assert(hosts, jc.DeepEquals, []interface{}{"a", "b"})
+
+	labels, err := s.settings.Map("labels")
+	c.Assert(err, gc.IsNil)
+	c.Assert(labels, jc.DeepEquals, map[string]interface{}{"k": "v"})
+}
+
+func (s *TypedSettingsSuite) TestErrors(c *gc.C) {
+	_, err := s.settings.String("unknown")
+	c.Assert(err, gc.ErrorMatches, `unknown option "unknown"`)
+
+	_, err = s.settings.Int("name")
+	c.Assert(err, gc.ErrorMatches, `option "name" has type "string", not int`)
+
+	_, err = s.settings.Bool("port")
+	c.Assert(err, gc.ErrorMatches, `option "port" has type "int", not boolean`)
+
+	_, err = s.settings.String("password")
+	c.Assert(err, gc.ErrorMatches, `option "password" is not set and has no default`)
+	c.Assert(charm.IsNotSetError(err), jc.IsTrue)
+	c.Assert(charm.IsNotSetError(nil), jc.IsFalse)
+}
+
+func (s *TypedSettingsSuite) TestInvalidValue(c *gc.C) {
+	config := &charm.Config{Options: map[string]charm.Option{
+		"port": {Type: "int"},
+	}}
+	settings := charm.NewTypedSettings(config, charm.Settings{"port": "eighty"})
+	_, err := settings.Int("port")
+	c.Assert(err, gc.ErrorMatches, `option "port" expected int, got "eighty"`)
+}
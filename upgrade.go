@@ -0,0 +1,123 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package charm
+
+import (
+	"fmt"
+	"sort"
+)
+
+// UpgradeContext describes how a deployed application uses its
+// charm, as needed by CheckUpgrade.
+type UpgradeContext struct {
+	// Relations holds the names of the charm relations
+	// that have been established.
+	Relations []string
+
+	// Storage maps the name of each store to the number of
+	// storage instances currently attached to it.
+	Storage map[string]int
+
+	// Config holds the config values that are currently set.
+	Config Settings
+}
+
+// UpgradeError holds the reasons why a charm upgrade
+// would break a deployment, as returned by CheckUpgrade.
+type UpgradeError struct {
+	Errors []error
+}
+
+func (err *UpgradeError) Error() string {
+	switch len(err.Errors) {
+	case 0:
+		return "no upgrade errors!"
+	case 1:
+		return err.Errors[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", err.Errors[0], len(err.Errors)-1)
+}
+
+// CheckUpgrade checks whether upgrading a deployment of the old charm,
+// used as described by inUse, to the new charm would break it. It
+// returns an *UpgradeError describing every problem found, or nil
+// if the upgrade is safe.
+func CheckUpgrade(old, new Charm, inUse UpgradeContext) error {
+	var errs []error
+	addErrorf := func(f string, a ...interface{}) {
+		errs = append(errs, fmt.Errorf(f, a...))
+	}
+
+	oldRelations := old.Meta().CombinedRelations()
+	relationNames := append([]string(nil), inUse.Relations...)
+	sort.Strings(relationNames)
+	for _, name := range relationNames {
+		rel, ok := oldRelations[name]
+		if !ok {
+			if name == "juju-info" {
+				// The implicit juju-info relation is always available.
+				continue
+			}
+			addErrorf("relation %q is established but not defined by the old charm", name)
+			continue
+		}
+		if !rel.ImplementedBy(new) {
+			addErrorf("relation %q is established but not implemented by the new charm", name)
+		}
+	}
+
+	newMeta := new.Meta()
+	storageNames := make([]string, 0, len(inUse.Storage))
+	for name := range inUse.Storage {
+		storageNames = append(storageNames, name)
+	}
+	sort.Strings(storageNames)
+	for _, name := range storageNames {
+		attached := inUse.Storage[name]
+		if attached == 0 {
+			continue
+		}
+		store, ok := newMeta.Storage[name]
+		if !ok {
+			addErrorf("storage %q is attached but removed from the new charm", name)
+			continue
+		}
+		if oldStore, ok := old.Meta().Storage[name]; ok && oldStore.Type != store.Type {
+			addErrorf("storage %q is attached but its type changed from %q to %q", name, oldStore.Type, store.Type)
+		}
+		if store.CountMax >= 0 && attached > store.CountMax {
+			addErrorf("storage %q has %d instances attached but the new charm allows at most %d", name, attached, store.CountMax)
+		}
+	}
+
+	oldOptions, newOptions := configOptions(old.Config()), configOptions(new.Config())
+	configNames := make([]string, 0, len(inUse.Config))
+	for name := range inUse.Config {
+		configNames = append(configNames, name)
+	}
+	sort.Strings(configNames)
+	for _, name := range configNames {
+		value := inUse.Config[name]
+		if value == nil {
+			continue
+		}
+		option, ok := newOptions[name]
+		if !ok {
+			addErrorf("config option %q is set but removed from the new charm", name)
+			continue
+		}
+		if oldOption, ok := oldOptions[name]; ok && oldOption.Type != option.Type {
+			addErrorf("config option %q is set but its type changed from %q to %q", name, oldOption.Type, option.Type)
+			continue
+		}
+		if _, err := option.validate(name, value); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return &UpgradeError{errs}
+	}
+	return nil
+}
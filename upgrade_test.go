@@ -0,0 +1,134 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package charm_test
+
+import (
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"gopkg.in/juju/charm.v6"
+)
+
+type UpgradeSuite struct{}
+
+var _ = gc.Suite(&UpgradeSuite{})
+
+const upgradeOldMeta = `
+name: foo
+summary: foo
+description: foo
+provides:
+  website: http
+  metrics: prometheus
+requires:
+  db: mysql
+  logs:
+    interface: syslog
+    scope: container
+storage:
+  data:
+    type: filesystem
+    multiple:
+      range: 0-4
+  cache:
+    type: block
+  scratch:
+    type: filesystem
+`
+
+const upgradeOldConfig = `
+options:
+  port: {type: int, default: 80}
+  name: {type: string}
+  debug: {type: boolean}
+  ratio: {type: float}
+`
+
+func (s *UpgradeSuite) TestCompatible(c *gc.C) {
+	old := newYAMLCharm(c, upgradeOldMeta, upgradeOldConfig, "")
+	new := newYAMLCharm(c, `
+name: foo
+summary: foo
+description: foo
+provides:
+  website: http
+requires:
+  db: mysql
+  logs:
+    interface: syslog
+    scope: container
+  extra: thing
+storage:
+  data:
+    type: filesystem
+    multiple:
+      range: 0-2
+  cache:
+    type: block
+`, `
+options:
+  port: {type: int}
+  name: {type: string}
+`, "")
+	err := charm.CheckUpgrade(old, new, charm.UpgradeContext{
+		Relations: []string{"website", "db", "logs", "juju-info"},
+		Storage:   map[string]int{"data": 2, "cache": 1, "scratch": 0},
+		Config:    charm.Settings{"port": int64(8080), "name": "bar", "debug": nil},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *UpgradeSuite) TestIncompatible(c *gc.C) {
+	old := newYAMLCharm(c, upgradeOldMeta, upgradeOldConfig, "")
+	new := newYAMLCharm(c, `
+name: foo
+summary: foo
+description: foo
+provides:
+  website: http
+requires:
+  db: pgsql
+  logs: syslog
+storage:
+  data:
+    type: filesystem
+    multiple:
+      range: 0-1
+  cache:
+    type: filesystem
+`, `
+options:
+  port: {type: string}
+  name: {type: string}
+`, "")
+	err := charm.CheckUpgrade(old, new, charm.UpgradeContext{
+		Relations: []string{"website", "metrics", "db", "logs", "nope"},
+		Storage:   map[string]int{"data": 2, "cache": 1, "scratch": 1},
+		Config:    charm.Settings{"port": int64(8080), "name": "bar", "debug": true},
+	})
+	c.Assert(err, gc.FitsTypeOf, &charm.UpgradeError{})
+	var messages []string
+	for _, err := range err.(*charm.UpgradeError).Errors {
+		messages = append(messages, err.Error())
+	}
+	c.Assert(messages, jc.DeepEquals, []string{
+		`relation "db" is established but not implemented by the new charm`,
+		`relation "metrics" is established but not implemented by the new charm`,
+		`relation "nope" is established but not defined by the old charm`,
+		`storage "cache" is attached but its type changed from "block" to "filesystem"`,
+		`storage "data" has 2 instances attached but the new charm allows at most 1`,
+		`storage "scratch" is attached but removed from the new charm`,
+		`config option "debug" is set but removed from the new charm`,
+		`config option "port" is set but its type changed from "int" to "string"`,
+	})
+	c.Assert(err, gc.ErrorMatches, `relation "db" is established but not implemented by the new charm \(and 7 more errors\)`)
+}
+
+func (s *UpgradeSuite) TestInvalidConfigValue(c *gc.C) {
+	ch := newYAMLCharm(c, upgradeOldMeta, upgradeOldConfig, "")
+	err := charm.CheckUpgrade(ch, ch, charm.UpgradeContext{
+		Config: charm.Settings{"port": "eighty"},
+	})
+	c.Assert(err, gc.ErrorMatches, `option "port" expected int, got "eighty"`)
+}
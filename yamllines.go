@@ -0,0 +1,103 @@
+// Copyright 2018 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package charm
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// yamlKeyPattern matches a mapping key at the start of a line.
+var yamlKeyPattern = regexp.MustCompile(`^("(?:[^"\\]|\\.)*"|'(?:[^']|'')*'|[^\s"'#\[{][^#]*?)\s*:(?:\s|$)`)
+
+// yamlLineFrame describes an enclosing node while scanning YAML lines.
+type yamlLineFrame struct {
+	indent int
+	path   string
+	item   bool
+}
+
+// yamlKeyLines returns the line numbers of the nodes of the given
+// YAML document, indexed by their slash-separated paths. Mapping
+// entries are named by their keys, and sequence items by their
+// indexes, so that, for example, "applications/mysql" and
+// "relations/0" may be found. Only block-style nodes are indexed;
+// nodes within flow-style collections or multi-line scalars are
+// not, so callers should fall back to the closest enclosing node.
+func yamlKeyLines(data []byte) map[string]int {
+	lines := make(map[string]int)
+	counts := make(map[string]int)
+	var stack []yamlLineFrame
+	parent := func() string {
+		if len(stack) == 0 {
+			return ""
+		}
+		return stack[len(stack)-1].path + "/"
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	// Lines within a literal or folded block scalar are skipped.
+	blockIndent := -1
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		text := strings.TrimRight(scanner.Text(), " \t\r")
+		rest := strings.TrimLeft(text, " ")
+		indent := len(text) - len(rest)
+		if rest == "" || strings.HasPrefix(rest, "#") || rest == "---" {
+			continue
+		}
+		if blockIndent >= 0 {
+			if indent > blockIndent {
+				continue
+			}
+			blockIndent = -1
+		}
+		for rest == "-" || strings.HasPrefix(rest, "- ") {
+			for len(stack) > 0 {
+				top := stack[len(stack)-1]
+				if top.indent < indent || top.indent == indent && !top.item {
+					break
+				}
+				stack = stack[:len(stack)-1]
+			}
+			p := strings.TrimSuffix(parent(), "/")
+			path := parent() + strconv.Itoa(counts[p])
+			counts[p]++
+			lines[path] = lineNum
+			stack = append(stack, yamlLineFrame{indent, path, true})
+			trimmed := strings.TrimLeft(rest[1:], " ")
+			indent += len(rest) - len(trimmed)
+			rest = trimmed
+		}
+		m := yamlKeyPattern.FindStringSubmatch(rest)
+		if m == nil {
+			continue
+		}
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		path := parent() + unquoteYAMLKey(m[1])
+		lines[path] = lineNum
+		stack = append(stack, yamlLineFrame{indent: indent, path: path})
+		value := strings.TrimSpace(rest[len(m[0]):])
+		if strings.HasPrefix(value, "|") || strings.HasPrefix(value, ">") {
+			blockIndent = indent
+		}
+	}
+	return lines
+}
+
+// unquoteYAMLKey returns the value of a possibly quoted mapping key.
+func unquoteYAMLKey(key string) string {
+	switch {
+	case strings.HasPrefix(key, `"`):
+		if s, err := strconv.Unquote(key); err == nil {
+			return s
+		}
+	case strings.HasPrefix(key, "'"):
+		return strings.Replace(key[1:len(key)-1], "''", "'", -1)
+	}
+	return key
+}